@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/Dirac-Software/ersummary/pkg/erd"
+	"gopkg.in/yaml.v3"
+)
+
+// A config file declares diagrams to generate in one run, with the databases
+// they are drawn from. Keys are named after the command line flags.
+//
+//	connections:
+//	  warehouse:
+//	    conn: ${WAREHOUSE_URL}
+//	diagrams:
+//	  - name: orders
+//	    connection: warehouse
+//	    tables: [orders, customers, addresses]
+//	    format: mermaid
+//	    output: docs/orders.mmd
+
+type config struct {
+	Connections map[string]connectionConfig `yaml:"connections"`
+	Diagrams    []diagramConfig             `yaml:"diagrams"`
+}
+
+// connectionConfig is a database, or a pg_dump schema file, to draw from.
+// Environment variables in conn are expanded, so that it needn't contain
+// passwords.
+type connectionConfig struct {
+	Conn       string `yaml:"conn"`
+	SchemaFile string `yaml:"schema-file"`
+}
+
+type diagramConfig struct {
+	Name           string   `yaml:"name"`
+	Connection     string   `yaml:"connection"`
+	Schemas        []string `yaml:"schemas"`
+	Tables         []string `yaml:"tables"`
+	TableRegex     string   `yaml:"table-regex"`
+	Depth          int      `yaml:"depth"`
+	DepthDirection string   `yaml:"depth-direction"`
+	DepthLimit     int      `yaml:"depth-limit"`
+	ShowColumns    bool     `yaml:"show-columns"`
+	ShowPathTables bool     `yaml:"show-path-tables"`
+	Paths          *int     `yaml:"paths"`    // 1 if not set, as for -paths
+	MaxHops        *int     `yaml:"max-hops"` // erd.DefaultMaxHops if not set
+	ExactTypes     bool     `yaml:"exact-types"`
+	Sort           string   `yaml:"sort"`
+	Format         string   `yaml:"format"`
+	Output         string   `yaml:"output"` // Standard output if not set
+}
+
+// loadConfig reads a config file, rejecting unknown keys so that typos don't
+// go unnoticed. Relative paths are made relative to the directory of the file.
+func loadConfig(path string) (*config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if len(cfg.Diagrams) == 0 {
+		return nil, fmt.Errorf("%s: no diagrams", path)
+	}
+	dir := filepath.Dir(path)
+	for name, connection := range cfg.Connections {
+		if (connection.Conn == "") == (connection.SchemaFile == "") {
+			return nil, fmt.Errorf("%s: connection %q needs either conn or schema-file", path, name)
+		}
+		connection.Conn = os.ExpandEnv(connection.Conn)
+		if connection.SchemaFile != "" && !filepath.IsAbs(connection.SchemaFile) {
+			connection.SchemaFile = filepath.Join(dir, connection.SchemaFile)
+		}
+		cfg.Connections[name] = connection
+	}
+	names := make(map[string]bool)
+	for i := range cfg.Diagrams {
+		diagram := &cfg.Diagrams[i]
+		if diagram.Name == "" {
+			return nil, fmt.Errorf("%s: diagram %d has no name", path, i+1)
+		}
+		if names[diagram.Name] {
+			return nil, fmt.Errorf("%s: diagram %q is declared twice", path, diagram.Name)
+		}
+		names[diagram.Name] = true
+		if _, ok := cfg.Connections[diagram.Connection]; !ok {
+			return nil, fmt.Errorf("%s: diagram %q has unknown connection %q", path, diagram.Name, diagram.Connection)
+		}
+		if diagram.Paths == nil {
+			paths := 1
+			diagram.Paths = &paths
+		}
+		if *diagram.Paths == 0 || *diagram.Paths < -1 {
+			return nil, fmt.Errorf("%s: diagram %q has invalid paths %d (-1 for all routes)", path, diagram.Name, *diagram.Paths)
+		}
+		if diagram.MaxHops == nil {
+			maxHops := erd.DefaultMaxHops
+			diagram.MaxHops = &maxHops
+		}
+		if *diagram.MaxHops < 1 {
+			return nil, fmt.Errorf("%s: diagram %q has invalid max-hops %d", path, diagram.Name, *diagram.MaxHops)
+		}
+		if diagram.Output != "" && !filepath.IsAbs(diagram.Output) {
+			diagram.Output = filepath.Join(dir, diagram.Output)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// runConfig generates the diagrams of a config file. Each database is opened
+// and its foreign keys fetched once, whatever the number of diagrams.
+func runConfig(path string, commandLine string) error {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+
+	introspectors := make(map[string]erd.Introspector)
+	for _, diagram := range cfg.Diagrams {
+		if _, ok := introspectors[diagram.Connection]; ok {
+			continue
+		}
+		connection := cfg.Connections[diagram.Connection]
+		introspector, closeIntrospector, err := openIntrospector(connection.Conn, connection.SchemaFile)
+		if err != nil {
+			return fmt.Errorf("connection %q: %w", diagram.Connection, err)
+		}
+		defer closeIntrospector()
+		introspectors[diagram.Connection] = erd.CacheForeignKeys(introspector)
+	}
+
+	for _, diagram := range cfg.Diagrams {
+		log.Printf("Generating diagram %s...", diagram.Name)
+		if err := runDiagram(diagram, introspectors[diagram.Connection], commandLine); err != nil {
+			return fmt.Errorf("diagram %q: %w", diagram.Name, err)
+		}
+	}
+	return nil
+}
+
+func runDiagram(diagram diagramConfig, introspector erd.Introspector, commandLine string) error {
+	if len(diagram.Tables) == 0 && diagram.TableRegex == "" {
+		return errors.New("either tables or table-regex must be set")
+	}
+	format := diagram.Format
+	if format == "" {
+		format = "mermaid"
+	}
+	renderer, ok := erd.LookupRenderer(format)
+	if !ok {
+		return fmt.Errorf("unknown output format %q", format)
+	}
+
+	output, err := generateDiagram(context.Background(), introspector, erd.Options{
+		Schemas:        diagram.Schemas,
+		Tables:         diagram.Tables,
+		TableRegex:     diagram.TableRegex,
+		Depth:          diagram.Depth,
+		DepthDirection: diagram.DepthDirection,
+		DepthLimit:     diagram.DepthLimit,
+		Paths:          *diagram.Paths,
+		MaxHops:        *diagram.MaxHops,
+		PathTables:     diagram.ShowPathTables,
+		Columns:        diagram.ShowColumns,
+		Sort:           diagram.Sort,
+	}, renderer, erd.RenderOptions{
+		CommandLine: fmt.Sprintf("%s (diagram %s)", commandLine, diagram.Name),
+		ExactTypes:  diagram.ExactTypes,
+	})
+	if err != nil {
+		return err
+	}
+
+	if diagram.Output == "" {
+		fmt.Println(output)
+		return nil
+	}
+	// As written to standard output
+	return os.WriteFile(diagram.Output, []byte(output+"\n"), 0o644)
+}
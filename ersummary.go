@@ -1,980 +1,180 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"strings"
-	"time"
 
+	"github.com/Dirac-Software/ersummary/pkg/erd"
 	_ "github.com/lib/pq"
-	"gonum.org/v1/gonum/graph"
-	"gonum.org/v1/gonum/graph/path"
-	"gonum.org/v1/gonum/graph/simple"
 )
 
-type Table struct {
-	Name    string
-	Schema  string
-	Columns []Column
-}
-
-type Column struct {
-	Name     string
-	DataType string
-	IsPK     bool
-	IsFK     bool
-}
-
-type ForeignKey struct {
-	FromSchema     string
-	FromTable      string
-	FromColumn     string
-	ToSchema       string
-	ToTable        string
-	ToColumn       string
-	ConstraintName string
-}
-
-type Cardinality struct {
-	Min string
-	Max string
-}
-
-type Relationship struct {
-	From            Table
-	To              Table
-	FromCardinality Cardinality
-	ToCardinality   Cardinality
-	Path            []string // Tables in the path
-}
-
-type ColumnInfo struct {
-	IsNullable          bool
-	HasUniqueConstraint bool
-}
-
 func main() {
+	var configFile string
 	var connStr string
+	var schemaFile string
 	var schemasStr string
 	var tablesStr string
 	var tableRegex string
 	var showColumns bool
-
+	var format string
+	var exactTypes bool
+	var depth int
+	var depthDirection string
+	var depthLimit int
+	var showPathTables bool
+	var maxPaths int
+	var maxHops int
+	var sortOrder string
+
+	flag.StringVar(&configFile, "config", "", "Generate the diagrams declared in a YAML config file, rather than one from the other flags")
 	flag.StringVar(&connStr, "conn", "", "PostgreSQL connection string")
+	flag.StringVar(&schemaFile, "schema-file", "", "Read the schema from a pg_dump --schema-only file instead of connecting to a database")
 	flag.StringVar(&schemasStr, "schema", "public", "Comma-separated list of database schemas")
 	flag.StringVar(&tablesStr, "tables", "", "Comma-separated list of table names (optionally schema-qualified: schema.table)")
 	flag.StringVar(&tableRegex, "table-regex", "", "Regular expression (ERE) to match table names")
+	flag.IntVar(&depth, "depth", 0, "Also select the tables within this many foreign keys of the selected ones")
+	flag.StringVar(&depthDirection, "depth-direction", "both", "Foreign keys to follow with -depth: out (to the tables referenced), in (from the tables referencing) or both")
+	flag.IntVar(&depthLimit, "depth-limit", 0, "Maximum number of tables -depth may add, closest first (0 for no limit)")
 	flag.BoolVar(&showColumns, "show-columns", false, "Show table columns in the diagram")
+	flag.BoolVar(&showPathTables, "show-path-tables", false, "Show the tables that relationships go through, with the foreign key of each hop, rather than labelling the relationships with them")
+	flag.IntVar(&maxPaths, "paths", 1, "Number of routes to draw between each pair of tables, shortest first (-1 for all routes within -max-hops)")
+	flag.IntVar(&maxHops, "max-hops", erd.DefaultMaxHops, "Maximum number of foreign keys on the routes found with -paths, other than the shortest")
+	flag.BoolVar(&exactTypes, "exact-types", false, "Show column types exactly as declared, e.g. varchar(255) rather than string")
+	flag.StringVar(&sortOrder, "sort", erd.SortAlpha, "Order of the tables: alpha (by name), schema (by schema, then name) or input (as named in -tables, then by name)")
+	flag.StringVar(&format, "format", "mermaid", "Output format: "+strings.Join(erd.Formats(), ", "))
 	flag.Parse()
 
-	if connStr == "" {
-		log.Fatal("Connection string is required")
-	}
-
-	if tablesStr == "" && tableRegex == "" {
-		log.Fatal("Either -tables or -table-regex must be specified")
-	}
-
-	schemas := strings.Split(schemasStr, ",")
-	for i := range schemas {
-		schemas[i] = strings.TrimSpace(schemas[i])
-	}
-
-	var tableNames []string
-	if tablesStr != "" {
-		tableNames = strings.Split(tablesStr, ",")
-		for i := range tableNames {
-			tableNames[i] = strings.TrimSpace(tableNames[i])
-		}
-	}
-
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		log.Fatal("Error connecting to database:", err)
-	}
-	defer db.Close()
-
-	err = db.Ping()
-	if err != nil {
-		log.Fatal("Error pinging database:", err)
-	}
-
-	// Get ALL foreign keys in the database to build complete graph
-	// (relationships may go through tables in other schemas)
-	allForeignKeys, err := getAllForeignKeys(db)
-	if err != nil {
-		log.Fatal("Error fetching all foreign keys:", err)
-	}
-
-	// Get list of tables matching the criteria
-	tables, err := getMatchingTables(db, schemas, tableNames, tableRegex)
-	if err != nil {
-		log.Fatal("Error fetching matching tables:", err)
-	}
-
-	if len(tables) == 0 {
-		log.Fatal("No tables matched the provided criteria")
-	}
-
-	// Extract qualified table names for filtering
-	qualifiedTableNames := make([]string, len(tables))
-	for i, t := range tables {
-		qualifiedTableNames[i] = getQualifiedName(t.Schema, t.Name)
-	}
-
-	// Filter foreign keys for selected tables (for column display)
-	selectedForeignKeys := filterForeignKeys(allForeignKeys, qualifiedTableNames)
-
-	relationships := calculateCardinalities(db, schemas, qualifiedTableNames, allForeignKeys)
-
-	var tableDetails []Table
-	if showColumns {
-		tableDetails, err = getTableColumns(db, tables, selectedForeignKeys)
-		if err != nil {
-			log.Fatal("Error fetching table columns:", err)
-		}
-	} else {
-		tableDetails = tables
-	}
+	erd.SetLogger(log.Default())
 
 	// Build command line for comment
-	cmdLine := append([]string{os.Args[0]}, os.Args[1:]...)
-	mermaidDiagram := generateMermaidDiagram(tableDetails, relationships, schemas[0], strings.Join(cmdLine, " "))
-	fmt.Println(mermaidDiagram)
-}
-
-func getMatchingTables(db *sql.DB, schemas []string, tableNames []string, tableRegex string) ([]Table, error) {
-	start := time.Now()
-	tableMap := make(map[string]Table) // Use map to deduplicate
-
-	// Handle exact table names from -tables option
-	if len(tableNames) > 0 {
-		for _, tableName := range tableNames {
-			// Check if table name is schema-qualified
-			if strings.Contains(tableName, ".") {
-				parts := strings.SplitN(tableName, ".", 2)
-				schema := parts[0]
-				table := parts[1]
-
-				// Query for this specific schema.table
-				query := `
-					SELECT table_schema, table_name
-					FROM information_schema.tables
-					WHERE table_schema = $1
-					AND table_name = $2
-					AND table_type = 'BASE TABLE'
-				`
-				rows, err := db.Query(query, schema, table)
-				if err != nil {
-					return nil, err
-				}
-				for rows.Next() {
-					var t Table
-					if err := rows.Scan(&t.Schema, &t.Name); err != nil {
-						rows.Close()
-						return nil, err
-					}
-					key := t.Schema + "." + t.Name
-					tableMap[key] = t
-				}
-				rows.Close()
-			} else {
-				// Unqualified table name - search in all specified schemas
-				schemaPlaceholders := make([]string, len(schemas))
-				args := make([]interface{}, len(schemas)+1)
-				for i, schema := range schemas {
-					schemaPlaceholders[i] = fmt.Sprintf("$%d", i+1)
-					args[i] = schema
-				}
-				args[len(schemas)] = tableName
-
-				query := fmt.Sprintf(`
-					SELECT table_schema, table_name
-					FROM information_schema.tables
-					WHERE table_schema IN (%s)
-					AND table_name = $%d
-					AND table_type = 'BASE TABLE'
-				`, strings.Join(schemaPlaceholders, ", "), len(schemas)+1)
-
-				rows, err := db.Query(query, args...)
-				if err != nil {
-					return nil, err
-				}
-				for rows.Next() {
-					var t Table
-					if err := rows.Scan(&t.Schema, &t.Name); err != nil {
-						rows.Close()
-						return nil, err
-					}
-					key := t.Schema + "." + t.Name
-					tableMap[key] = t
-				}
-				rows.Close()
-			}
-		}
-	}
-
-	// Handle regex pattern from -table-regex option
-	if tableRegex != "" {
-		schemaPlaceholders := make([]string, len(schemas))
-		args := make([]interface{}, len(schemas)+1)
-		for i, schema := range schemas {
-			schemaPlaceholders[i] = fmt.Sprintf("$%d", i+1)
-			args[i] = schema
-		}
-		args[len(schemas)] = tableRegex
-
-		query := fmt.Sprintf(`
-			SELECT table_schema, table_name
-			FROM information_schema.tables
-			WHERE table_schema IN (%s)
-			AND table_name ~ $%d
-			AND table_type = 'BASE TABLE'
-		`, strings.Join(schemaPlaceholders, ", "), len(schemas)+1)
-
-		rows, err := db.Query(query, args...)
-		if err != nil {
-			return nil, err
-		}
-		for rows.Next() {
-			var t Table
-			if err := rows.Scan(&t.Schema, &t.Name); err != nil {
-				rows.Close()
-				return nil, err
+	cmdLine := strings.Join(append([]string{os.Args[0]}, os.Args[1:]...), " ")
+
+	if configFile != "" {
+		// Settings come from the config file only
+		var others []string
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name != "config" {
+				others = append(others, "-"+f.Name)
 			}
-			key := t.Schema + "." + t.Name
-			tableMap[key] = t
+		})
+		if len(others) > 0 {
+			log.Fatalf("-config can't be used with %s", strings.Join(others, ", "))
 		}
-		rows.Close()
-	}
-
-	// Convert map to slice
-	tables := make([]Table, 0, len(tableMap))
-	for _, t := range tableMap {
-		tables = append(tables, t)
-	}
-
-	log.Printf("Found %d tables matching criteria (took %v)", len(tables), time.Since(start))
-	return tables, nil
-}
-
-func getAllForeignKeys(db *sql.DB) ([]ForeignKey, error) {
-	query := `
-		SELECT
-			tc.table_schema AS from_schema,
-			tc.table_name AS from_table,
-			kcu.column_name AS from_column,
-			ccu.table_schema AS to_schema,
-			ccu.table_name AS to_table,
-			ccu.column_name AS to_column,
-			tc.constraint_name
-		FROM
-			information_schema.table_constraints AS tc
-			JOIN information_schema.key_column_usage AS kcu
-				ON tc.constraint_name = kcu.constraint_name
-				AND tc.table_schema = kcu.table_schema
-			JOIN information_schema.constraint_column_usage AS ccu
-				ON ccu.constraint_name = tc.constraint_name
-		WHERE
-			tc.constraint_type = 'FOREIGN KEY'
-	`
-
-	log.Printf("Fetching all foreign keys from database...")
-	start := time.Now()
-	rows, err := db.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var foreignKeys []ForeignKey
-	for rows.Next() {
-		var fk ForeignKey
-		err := rows.Scan(&fk.FromSchema, &fk.FromTable, &fk.FromColumn, &fk.ToSchema, &fk.ToTable, &fk.ToColumn, &fk.ConstraintName)
-		if err != nil {
-			return nil, err
+		if err := runConfig(configFile, cmdLine); err != nil {
+			log.Fatalf("Error %v", err)
 		}
-		foreignKeys = append(foreignKeys, fk)
-	}
-
-	log.Printf("Found %d foreign keys in database (took %v)", len(foreignKeys), time.Since(start))
-	return foreignKeys, rows.Err()
-}
-
-func getQualifiedName(schema, table string) string {
-	if schema == "public" || schema == "" {
-		return table
-	}
-	// Use dot internally for unambiguous parsing
-	return schema + "." + table
-}
-
-func filterForeignKeys(allForeignKeys []ForeignKey, tables []string) []ForeignKey {
-	tableSet := make(map[string]bool)
-	for _, table := range tables {
-		tableSet[table] = true
+		return
 	}
 
-	var filtered []ForeignKey
-	for _, fk := range allForeignKeys {
-		fromQualified := getQualifiedName(fk.FromSchema, fk.FromTable)
-		toQualified := getQualifiedName(fk.ToSchema, fk.ToTable)
-		if tableSet[fromQualified] && tableSet[toQualified] {
-			filtered = append(filtered, fk)
-		}
+	if connStr == "" && schemaFile == "" {
+		log.Fatal("Either -config, -conn or -schema-file must be specified")
 	}
 
-	log.Printf("Filtered %d foreign keys for selected tables (from %d total)", len(filtered), len(allForeignKeys))
-	return filtered
-}
-
-type TableNode struct {
-	id   int64
-	name string
-}
-
-func (n TableNode) ID() int64 {
-	return n.id
-}
-
-func calculateCardinalities(db *sql.DB, schemas []string, selectedTables []string, allForeignKeys []ForeignKey) []Relationship {
-	if len(allForeignKeys) == 0 {
-		return []Relationship{}
-	}
-
-	// Use first schema for backward compatibility in relationship generation
-	schema := schemas[0]
-
-	// Build directed graph using gonum
-	g := simple.NewDirectedGraph()
-	tableToNode := make(map[string]graph.Node)
-	nodeToTable := make(map[int64]string)
-	nodeID := int64(0)
-
-	// Add all tables as nodes
-	for _, fk := range allForeignKeys {
-		fromQualified := getQualifiedName(fk.FromSchema, fk.FromTable)
-		toQualified := getQualifiedName(fk.ToSchema, fk.ToTable)
-
-		if _, exists := tableToNode[fromQualified]; !exists {
-			node := TableNode{id: nodeID, name: fromQualified}
-			g.AddNode(node)
-			tableToNode[fromQualified] = node
-			nodeToTable[nodeID] = fromQualified
-			nodeID++
-		}
-		if _, exists := tableToNode[toQualified]; !exists {
-			node := TableNode{id: nodeID, name: toQualified}
-			g.AddNode(node)
-			tableToNode[toQualified] = node
-			nodeToTable[nodeID] = toQualified
-			nodeID++
-		}
-	}
-
-	// Add edges for foreign keys (inverted direction)
-	// FK goes from child to parent, but we want edges from parent to child
-	// to find common descendants
-	for _, fk := range allForeignKeys {
-		fromQualified := getQualifiedName(fk.FromSchema, fk.FromTable)
-		toQualified := getQualifiedName(fk.ToSchema, fk.ToTable)
-		if fromQualified == toQualified {
-			continue // Skip self-references
-		}
-		fromNode := tableToNode[fromQualified]
-		toNode := tableToNode[toQualified]
-		// Invert the edge direction: parent -> child
-		g.SetEdge(g.NewEdge(toNode, fromNode))
+	if tablesStr == "" && tableRegex == "" {
+		log.Fatal("Either -tables or -table-regex must be specified")
 	}
 
-	// Use gonum's Floyd-Warshall
-	allPaths, ok := path.FloydWarshall(g)
+	renderer, ok := erd.LookupRenderer(format)
 	if !ok {
-		log.Printf("Floyd-Warshall found negative cycles")
-		return []Relationship{}
-	}
-
-	// Get column info for all FK columns in one query
-	columnInfo, err := getColumnInfo(db, schemas, allForeignKeys)
-	if err != nil {
-		log.Printf("Error fetching column info: %v", err)
-		return []Relationship{}
-	}
-
-	// Create FK lookup map using qualified names
-	fkMap := make(map[string]ForeignKey)
-	for _, fk := range allForeignKeys {
-		fromQualified := getQualifiedName(fk.FromSchema, fk.FromTable)
-		toQualified := getQualifiedName(fk.ToSchema, fk.ToTable)
-		fkMap[fromQualified+"->"+toQualified] = fk
-	}
-
-	// Create map of selected tables for quick lookup
-	selectedMap := make(map[string]bool)
-	for _, t := range selectedTables {
-		selectedMap[t] = true
-	}
-
-	var relationships []Relationship
-
-	// Find relationships between all pairs of selected tables
-	for i, tableA := range selectedTables {
-		for j, tableB := range selectedTables {
-			if i >= j { // Skip self and already processed pairs
-				continue
-			}
-
-			nodeA, okA := tableToNode[tableA]
-			nodeB, okB := tableToNode[tableB]
-			if !okA || !okB {
-				continue
-			}
-
-			// Find common descendant (highest table with FKs to both A and B)
-			lca, lcaNode := findLCAUsingGonum(tableA, tableB, g, tableToNode, nodeToTable, selectedMap, allPaths)
-			if lca != "" {
-				// Get paths from A to C and B to C (inverted graph)
-				pathAtoC, _, _ := allPaths.Between(nodeA.ID(), lcaNode.ID())
-				pathBtoC, _, _ := allPaths.Between(nodeB.ID(), lcaNode.ID())
-
-				if len(pathAtoC) > 0 && len(pathBtoC) > 0 {
-					// Convert to string paths and reverse them to get C->A and C->B
-					strPathAtoC := nodesToTables(pathAtoC, nodeToTable)
-					strPathBtoC := nodesToTables(pathBtoC, nodeToTable)
-
-					// Reverse paths to get the actual FK direction
-					strPathCtoA := make([]string, len(strPathAtoC))
-					strPathCtoB := make([]string, len(strPathBtoC))
-					for i := range strPathAtoC {
-						strPathCtoA[i] = strPathAtoC[len(strPathAtoC)-1-i]
-					}
-					for i := range strPathBtoC {
-						strPathCtoB[i] = strPathBtoC[len(strPathBtoC)-1-i]
-					}
-
-					// Calculate combined cardinality
-					relationship := calculateLCACardinality(lca, tableA, tableB, strPathCtoA, strPathCtoB, fkMap, columnInfo, schema)
-					if relationship != nil {
-						relationships = append(relationships, *relationship)
-					}
-				}
-			} else {
-				// Direct path between A and B (considering inverted graph)
-				rel := findDirectPath(nodeA, nodeB, tableA, tableB, allPaths, nodeToTable, selectedMap, fkMap, columnInfo, schema)
-				if rel != nil {
-					relationships = append(relationships, *rel)
-				}
-			}
-		}
+		log.Fatalf("Unknown output format %q", format)
 	}
 
-	return relationships
-}
-
-func nodesToTables(nodes []graph.Node, nodeToTable map[int64]string) []string {
-	tables := make([]string, len(nodes))
-	for i, node := range nodes {
-		tables[i] = nodeToTable[node.ID()]
-	}
-	return tables
-}
-
-func isValidPath(path []string, selectedMap map[string]bool) bool {
-	// Check intermediate nodes (not start or end)
-	for i := 1; i < len(path)-1; i++ {
-		if selectedMap[path[i]] {
-			return false
-		}
-	}
-	return true
-}
-
-func findDirectPath(nodeA, nodeB graph.Node, tableA, tableB string, allPaths path.AllShortest, nodeToTable map[int64]string, selectedMap map[string]bool, fkMap map[string]ForeignKey, columnInfo map[string]ColumnInfo, schema string) *Relationship {
-	// Try path from B to A (means A has FK to B in inverted graph)
-	if rel := tryDirectPath(nodeB, nodeA, allPaths, nodeToTable, selectedMap, fkMap, columnInfo, schema); rel != nil {
-		return rel
-	}
-
-	// Try path from A to B (means B has FK to A in inverted graph)
-	return tryDirectPath(nodeA, nodeB, allPaths, nodeToTable, selectedMap, fkMap, columnInfo, schema)
-}
-
-func tryDirectPath(fromNode, toNode graph.Node, allPaths path.AllShortest, nodeToTable map[int64]string, selectedMap map[string]bool, fkMap map[string]ForeignKey, columnInfo map[string]ColumnInfo, schema string) *Relationship {
-	path, _, _ := allPaths.Between(fromNode.ID(), toNode.ID())
-	if len(path) == 0 {
-		return nil
-	}
-
-	strPath := nodesToTables(path, nodeToTable)
-	if !isValidPath(strPath, selectedMap) {
-		return nil
+	if depthDirection != "both" && depthDirection != "out" && depthDirection != "in" {
+		log.Fatalf("Unknown -depth-direction %q", depthDirection)
 	}
 
-	// Reverse to get actual FK direction (graph is inverted)
-	reversedPath := make([]string, len(strPath))
-	for i := range strPath {
-		reversedPath[i] = strPath[len(strPath)-1-i]
+	if sortOrder != erd.SortAlpha && sortOrder != erd.SortSchema && sortOrder != erd.SortInput {
+		log.Fatalf("Unknown -sort %q", sortOrder)
 	}
 
-	relationship := calculatePathCardinality(reversedPath, fkMap, columnInfo, schema)
-	if relationship != nil {
-		relationship.Path = reversedPath
+	if maxPaths == 0 || maxPaths < -1 {
+		log.Fatalf("Invalid -paths %d (-1 for all routes)", maxPaths)
 	}
-	return relationship
-}
-
-func findLCAUsingGonum(tableA, tableB string, g graph.Directed, tableToNode map[string]graph.Node, nodeToTable map[int64]string, selectedMap map[string]bool, allPaths path.AllShortest) (string, graph.Node) {
-	nodeA := tableToNode[tableA]
-	nodeB := tableToNode[tableB]
-
-	// Find all nodes that can reach both A and B (common descendants)
-	// Since graph is inverted, paths from C to A mean C has FK path to A
-	var candidates []graph.Node
-	nodes := g.Nodes()
-	for nodes.Next() {
-		node := nodes.Node()
-
-		// Check if A and B can reach this node (meaning this node has FKs to A and B)
-		pathFromA, _, _ := allPaths.Between(nodeA.ID(), node.ID())
-		pathFromB, _, _ := allPaths.Between(nodeB.ID(), node.ID())
-
-		if len(pathFromA) > 0 && len(pathFromB) > 0 {
-			// Check if paths don't go through other selected tables
-			strPathFromA := nodesToTables(pathFromA, nodeToTable)
-			strPathFromB := nodesToTables(pathFromB, nodeToTable)
-
-			if isValidPath(strPathFromA, selectedMap) && isValidPath(strPathFromB, selectedMap) {
-				candidates = append(candidates, node)
-			}
-		}
+	if maxHops < 1 {
+		log.Fatalf("Invalid -max-hops %d", maxHops)
 	}
 
-	// Among candidates, find the one with minimum total distance
-	if len(candidates) == 0 {
-		return "", nil
+	schemas := strings.Split(schemasStr, ",")
+	for i := range schemas {
+		schemas[i] = strings.TrimSpace(schemas[i])
 	}
 
-	var bestLCA graph.Node
-	minDist := float64(1000000)
-
-	for _, candidate := range candidates {
-		distToA := allPaths.Weight(candidate.ID(), nodeA.ID())
-		distToB := allPaths.Weight(candidate.ID(), nodeB.ID())
-		totalDist := distToA + distToB
-
-		if totalDist < minDist {
-			minDist = totalDist
-			bestLCA = candidate
+	var tableNames []string
+	if tablesStr != "" {
+		tableNames = strings.Split(tablesStr, ",")
+		for i := range tableNames {
+			tableNames[i] = strings.TrimSpace(tableNames[i])
 		}
 	}
 
-	if bestLCA == nil {
-		return "", nil
-	}
-
-	return nodeToTable[bestLCA.ID()], bestLCA
-}
-
-func calculateLCACardinality(lca, tableA, tableB string, pathCtoA, pathCtoB []string, fkMap map[string]ForeignKey, columnInfo map[string]ColumnInfo, schema string) *Relationship {
-	// Calculate cardinalities along both paths
-	cardCtoA := calculatePathCardinality(pathCtoA, fkMap, columnInfo, schema)
-	cardCtoB := calculatePathCardinality(pathCtoB, fkMap, columnInfo, schema)
-
-	if cardCtoA == nil || cardCtoB == nil {
-		return nil
-	}
-
-	// Combine cardinalities through the LCA
-	// The relationship from A to B through C depends on both paths
-	// If C->A is 1:* and C->B is 1:*, then A->B is *:*
-	fromMin := "0"
-	fromMax := "*"
-	toMin := "0"
-	toMax := "*"
-
-	// If both paths have required relationships (min = 1), then the combined is also required
-	if cardCtoA.ToCardinality.Min == "1" && cardCtoB.ToCardinality.Min == "1" {
-		fromMin = "1"
-		toMin = "1"
-	}
-
-	// Build the complete path
-	fullPath := make([]string, 0)
-	// Reverse path from A to C
-	for i := len(pathCtoA) - 1; i >= 0; i-- {
-		fullPath = append(fullPath, pathCtoA[i])
-	}
-	// Add path from C to B (excluding C which is already in the path)
-	if len(pathCtoB) > 1 {
-		fullPath = append(fullPath, pathCtoB[1:]...)
-	}
-
-	// Parse schema from qualified table names
-	schemaA, nameA := parseQualifiedName(tableA)
-	schemaB, nameB := parseQualifiedName(tableB)
-
-	return &Relationship{
-		From:            Table{Name: nameA, Schema: schemaA},
-		To:              Table{Name: nameB, Schema: schemaB},
-		FromCardinality: Cardinality{Min: fromMin, Max: fromMax},
-		ToCardinality:   Cardinality{Min: toMin, Max: toMax},
-		Path:            fullPath,
-	}
-}
-
-func getColumnInfo(db *sql.DB, schemas []string, foreignKeys []ForeignKey) (map[string]ColumnInfo, error) {
-	if len(foreignKeys) == 0 {
-		return make(map[string]ColumnInfo), nil
-	}
-
-	// Build column info query with schema, table, and column
-	var columnSpecs []string
-	for _, fk := range foreignKeys {
-		qualifiedName := getQualifiedName(fk.FromSchema, fk.FromTable)
-		columnSpecs = append(columnSpecs, fmt.Sprintf("('%s', '%s', '%s', '%s')", fk.FromSchema, fk.FromTable, fk.FromColumn, qualifiedName+"."+fk.FromColumn))
-	}
-
-	query := fmt.Sprintf(`
-		WITH fk_columns AS (
-			SELECT * FROM (VALUES %s) AS t(table_schema, table_name, column_name, table_column)
-		),
-		column_info AS (
-			SELECT
-				fk.table_column,
-				c.is_nullable = 'YES' as is_nullable,
-				EXISTS (
-					SELECT 1
-					FROM information_schema.table_constraints tc
-					JOIN information_schema.key_column_usage kcu
-						ON tc.constraint_name = kcu.constraint_name
-					WHERE tc.table_schema = fk.table_schema
-						AND tc.table_name = fk.table_name
-						AND kcu.column_name = fk.column_name
-						AND tc.constraint_type IN ('PRIMARY KEY', 'UNIQUE')
-						-- Check that this is the only column in the constraint
-						AND NOT EXISTS (
-							SELECT 1
-							FROM information_schema.key_column_usage kcu2
-							WHERE kcu2.constraint_name = tc.constraint_name
-								AND kcu2.table_schema = tc.table_schema
-								AND kcu2.column_name != fk.column_name
-						)
-				) as has_unique_constraint
-			FROM fk_columns fk
-			JOIN information_schema.columns c
-				ON c.table_schema = fk.table_schema
-				AND c.table_name = fk.table_name
-				AND c.column_name = fk.column_name
-		)
-		SELECT table_column, is_nullable, has_unique_constraint
-		FROM column_info
-	`, strings.Join(columnSpecs, ", "))
-
-	log.Printf("Fetching column info for %d foreign key columns...", len(foreignKeys))
-	start := time.Now()
-	rows, err := db.Query(query)
+	introspector, closeIntrospector, err := openIntrospector(connStr, schemaFile)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	columnInfo := make(map[string]ColumnInfo)
-
-	for rows.Next() {
-		var tableColumn string
-		var isNullable, hasUnique bool
-		if err := rows.Scan(&tableColumn, &isNullable, &hasUnique); err != nil {
-			return nil, err
-		}
-		columnInfo[tableColumn] = ColumnInfo{
-			IsNullable:          isNullable,
-			HasUniqueConstraint: hasUnique,
-		}
-	}
-
-	log.Printf("Retrieved column info for %d columns (took %v)", len(columnInfo), time.Since(start))
-	return columnInfo, nil
-}
-
-func calculatePathCardinality(pathTables []string, fkMap map[string]ForeignKey, columnInfo map[string]ColumnInfo, schema string) *Relationship {
-	if len(pathTables) < 2 {
-		return nil
-	}
-
-	// For now, we'll calculate cardinality for direct relationships
-	// This can be extended to handle multi-hop paths
-	if len(pathTables) == 2 {
-		fromTable := pathTables[0]
-		toTable := pathTables[1]
-
-		// Check both directions for FK
-		if fk, exists := fkMap[fromTable+"->"+toTable]; exists {
-			return calculateDirectCardinality(fromTable, toTable, fk, columnInfo, schema)
-		} else if fk, exists := fkMap[toTable+"->"+fromTable]; exists {
-			// Swap the tables to get the correct direction
-			rel := calculateDirectCardinality(toTable, fromTable, fk, columnInfo, schema)
-			if rel != nil {
-				// Swap the relationship direction
-				return &Relationship{
-					From:            rel.To,
-					To:              rel.From,
-					FromCardinality: rel.ToCardinality,
-					ToCardinality:   rel.FromCardinality,
-				}
-			}
-			return nil
-		}
-	}
-
-	// For multi-hop paths, aggregate cardinalities
-	// This is a simplified version - you might want to implement more sophisticated logic
-	fromSchema, fromName := parseQualifiedName(pathTables[0])
-	toSchema, toName := parseQualifiedName(pathTables[len(pathTables)-1])
-
-	return &Relationship{
-		From:            Table{Name: fromName, Schema: fromSchema},
-		To:              Table{Name: toName, Schema: toSchema},
-		FromCardinality: Cardinality{Min: "0", Max: "*"},
-		ToCardinality:   Cardinality{Min: "0", Max: "*"},
-	}
-}
-
-func calculateDirectCardinality(fromTable, toTable string, fk ForeignKey, columnInfo map[string]ColumnInfo, schema string) *Relationship {
-	// Build qualified column lookup key
-	fromQualified := getQualifiedName(fk.FromSchema, fk.FromTable)
-	tableColumn := fromQualified + "." + fk.FromColumn
-	info, found := columnInfo[tableColumn]
-
-	min := "0"
-	max := "*"
-
-	if found {
-		if !info.IsNullable {
-			min = "1"
-		}
-		if info.HasUniqueConstraint {
-			max = "1"
-		}
-	}
-
-	// Parse schema from qualified table names
-	fromSchema, fromName := parseQualifiedName(fromTable)
-	toSchema, toName := parseQualifiedName(toTable)
-
-	return &Relationship{
-		From:            Table{Name: fromName, Schema: fromSchema},
-		To:              Table{Name: toName, Schema: toSchema},
-		FromCardinality: Cardinality{Min: min, Max: max},
-		ToCardinality:   Cardinality{Min: "1", Max: "1"},
-	}
-}
-
-func parseQualifiedName(qualifiedName string) (string, string) {
-	parts := strings.SplitN(qualifiedName, ".", 2)
-	if len(parts) == 2 {
-		return parts[0], parts[1]
-	}
-	return "public", parts[0]
-}
-
-func getTableColumns(db *sql.DB, tables []Table, foreignKeys []ForeignKey) ([]Table, error) {
-	if len(tables) == 0 {
-		return []Table{}, nil
-	}
-
-	// Create FK lookup map using qualified names
-	fkLookup := make(map[string]bool)
-	for _, fk := range foreignKeys {
-		qualifiedName := getQualifiedName(fk.FromSchema, fk.FromTable)
-		fkLookup[qualifiedName+"."+fk.FromColumn] = true
-	}
-
-	// Build WHERE conditions for each table
-	var conditions []string
-	args := make([]interface{}, 0, len(tables)*2)
-	argIndex := 1
-	for _, table := range tables {
-		conditions = append(conditions, fmt.Sprintf("(c.table_schema = $%d AND c.table_name = $%d)", argIndex, argIndex+1))
-		args = append(args, table.Schema, table.Name)
-		argIndex += 2
-	}
-
-	query := fmt.Sprintf(`
-		SELECT
-			c.table_schema,
-			c.table_name,
-			c.column_name,
-			c.data_type,
-			COALESCE(tc.constraint_type = 'PRIMARY KEY', false) as is_pk
-		FROM
-			information_schema.columns c
-		LEFT JOIN information_schema.key_column_usage kcu
-			ON c.table_schema = kcu.table_schema
-			AND c.table_name = kcu.table_name
-			AND c.column_name = kcu.column_name
-		LEFT JOIN information_schema.table_constraints tc
-			ON kcu.constraint_name = tc.constraint_name
-			AND kcu.table_schema = tc.table_schema
-			AND tc.constraint_type = 'PRIMARY KEY'
-		WHERE
-			(%s)
-		ORDER BY
-			c.table_schema,
-			c.table_name,
-			c.ordinal_position
-	`, strings.Join(conditions, " OR "))
-
-	log.Printf("Fetching table columns for %d tables", len(tables))
-	start := time.Now()
-	rows, err := db.Query(query, args...)
+		log.Fatalf("Error %v", err)
+	}
+	defer closeIntrospector()
+
+	options := erd.Options{
+		Schemas:        schemas,
+		Tables:         tableNames,
+		TableRegex:     tableRegex,
+		Depth:          depth,
+		DepthDirection: depthDirection,
+		DepthLimit:     depthLimit,
+		Paths:          maxPaths,
+		MaxHops:        maxHops,
+		PathTables:     showPathTables,
+		Columns:        showColumns,
+		Sort:           sortOrder,
+	}
+
+	diagram, err := generateDiagram(context.Background(), introspector, options, renderer, erd.RenderOptions{
+		CommandLine: cmdLine,
+		ExactTypes:  exactTypes,
+	})
 	if err != nil {
-		return nil, err
+		log.Fatalf("Error %v", err)
 	}
-	defer rows.Close()
-
-	tableMap := make(map[string]*Table)
-
-	for rows.Next() {
-		var schemaName, tableName, columnName, dataType string
-		var isPK bool
-		err := rows.Scan(&schemaName, &tableName, &columnName, &dataType, &isPK)
-		if err != nil {
-			return nil, err
-		}
-
-		tableKey := schemaName + "." + tableName
-		if _, ok := tableMap[tableKey]; !ok {
-			tableMap[tableKey] = &Table{Name: tableName, Schema: schemaName, Columns: []Column{}}
-		}
-
-		// Use qualified name for FK lookup
-		qualifiedTableName := getQualifiedName(schemaName, tableName)
-		isFK := fkLookup[qualifiedTableName+"."+columnName]
-		tableMap[tableKey].Columns = append(tableMap[tableKey].Columns, Column{
-			Name:     columnName,
-			DataType: dataType,
-			IsPK:     isPK,
-			IsFK:     isFK,
-		})
-	}
-
-	var result []Table
-	for _, table := range tableMap {
-		result = append(result, *table)
-	}
-
-	log.Printf("Retrieved column details for %d tables (took %v)", len(result), time.Since(start))
-	return result, rows.Err()
+	fmt.Println(diagram)
 }
 
-func getQualifiedTableName(table Table) string {
-	// Get qualified name with dots, then convert to underscores for Mermaid
-	// Mermaid only allows alphanumeric and underscore in entity names
-	qualifiedName := getQualifiedName(table.Schema, table.Name)
-	return strings.ReplaceAll(qualifiedName, ".", "_")
-}
-
-func generateMermaidDiagram(tables []Table, relationships []Relationship, schema string, commandLine string) string {
-	var sb strings.Builder
-
-	// Add comments at the top
-	sb.WriteString("%%{init: {'theme':'neutral'}}%%\n")
-	sb.WriteString("%% Generated by https://github.com/Dirac-Software/ersummary\n")
-	sb.WriteString(fmt.Sprintf("%%%% Command: %s\n", commandLine))
-	sb.WriteString("\nerDiagram\n")
-
-	for _, table := range tables {
-		qualifiedName := getQualifiedTableName(table)
-		sb.WriteString(fmt.Sprintf("    %s {\n", qualifiedName))
-		if len(table.Columns) > 0 {
-			for _, col := range table.Columns {
-				keyIndicator := ""
-				if col.IsPK && col.IsFK {
-					keyIndicator = "PK,FK"
-				} else if col.IsPK {
-					keyIndicator = "PK"
-				} else if col.IsFK {
-					keyIndicator = "FK"
-				}
-				sb.WriteString(fmt.Sprintf("        %s %s %s\n", dataTypeToMermaid(col.DataType), col.Name, keyIndicator))
-			}
+// openIntrospector reads a schema file, or connects to a database
+func openIntrospector(connStr, schemaFile string) (erd.Introspector, func(), error) {
+	if schemaFile != "" {
+		catalog, err := erd.LoadSchemaFile(schemaFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading schema file: %w", err)
 		}
-		sb.WriteString("    }\n")
+		return catalog, func() {}, nil
 	}
 
-	for _, rel := range relationships {
-		relType := getMermaidRelationType(rel.FromCardinality, rel.ToCardinality)
-		label := ""
-		if len(rel.Path) > 2 {
-			label = fmt.Sprintf("via %s", strings.Join(rel.Path[1:len(rel.Path)-1], ", "))
-		}
-		fromName := getQualifiedTableName(rel.From)
-		toName := getQualifiedTableName(rel.To)
-		sb.WriteString(fmt.Sprintf("    %s %s %s : \"%s\"\n",
-			fromName,
-			relType,
-			toName,
-			label))
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connecting to database: %w", err)
 	}
-
-	return sb.String()
-}
-
-func dataTypeToMermaid(pgType string) string {
-	switch {
-	case strings.Contains(pgType, "int"):
-		return "int"
-	case strings.Contains(pgType, "char"), strings.Contains(pgType, "text"):
-		return "string"
-	case strings.Contains(pgType, "timestamp"), strings.Contains(pgType, "date"), strings.Contains(pgType, "time"):
-		return "datetime"
-	case strings.Contains(pgType, "bool"):
-		return "boolean"
-	case strings.Contains(pgType, "numeric"), strings.Contains(pgType, "decimal"), strings.Contains(pgType, "real"), strings.Contains(pgType, "double"):
-		return "float"
-	default:
-		return pgType
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("pinging database: %w", err)
 	}
+	return erd.NewPostgresIntrospector(db), func() { db.Close() }, nil
 }
 
-func getMermaidRelationType(fromCard, toCard Cardinality) string {
-	return getCardinalitySymbol(fromCard) + "--" + reverseString(getCardinalitySymbol(toCard))
-}
-
-func getCardinalitySymbol(card Cardinality) string {
-	minMax := card.Min + card.Max
-	switch minMax {
-	case "01":
-		return "|o"
-	case "11":
-		return "||"
-	case "0*":
-		return "}o"
-	case "1*":
-		return "}|"
-	default:
-		log.Printf("Unexpected cardinality: min=%s, max=%s", card.Min, card.Max)
-		return "||"
+// generateDiagram analyzes the tables and renders them, with their columns if
+// asked for or if the format needs them
+func generateDiagram(ctx context.Context, introspector erd.Introspector, options erd.Options, renderer erd.Renderer, renderOptions erd.RenderOptions) (string, error) {
+	options.Columns = options.Columns || renderer.NeedsColumns
+	result, err := erd.Analyze(ctx, introspector, options)
+	if err != nil {
+		return "", fmt.Errorf("analyzing tables: %w", err)
 	}
-}
 
-func reverseString(s string) string {
-	runes := []rune(s)
-	// First reverse the string
-	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
-		runes[i], runes[j] = runes[j], runes[i]
-	}
-	// Then flip the curly braces
-	for i := range runes {
-		switch runes[i] {
-		case '{':
-			runes[i] = '}'
-		case '}':
-			runes[i] = '{'
-		}
+	diagram, err := renderer.Render(result.Tables, result.Relationships, renderOptions)
+	if err != nil {
+		return "", fmt.Errorf("rendering diagram: %w", err)
 	}
-	return string(runes)
+	return diagram, nil
 }
@@ -5,6 +5,7 @@ go 1.21
 require (
 	github.com/lib/pq v1.10.9
 	gonum.org/v1/gonum v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require golang.org/x/exp v0.0.0-20230321023759-10a507213a29 // indirect
@@ -0,0 +1,344 @@
+package erd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/graph"
+)
+
+// DefaultMaxHops is the length of the longest routes found, other than the
+// shortest, when Options.Paths isn't 1, unless Options.MaxHops is set
+const DefaultMaxHops = 4
+
+// Options select the tables to analyze and what to find out about them
+type Options struct {
+	Schemas    []string // Schemas to look for unqualified table names in, public if empty
+	Tables     []string // Table names, optionally schema-qualified (schema.table)
+	TableRegex string   // Regular expression (ERE) matching table names
+
+	// Depth also selects the tables within this many foreign keys of the
+	// selected ones, following DepthDirection: out (to the tables referenced),
+	// in (from the tables referencing) or both, the default. Unless DepthLimit
+	// is 0, at most DepthLimit tables are added, the closest first.
+	Depth          int
+	DepthDirection string
+	DepthLimit     int
+
+	// Paths is the number of routes found between each pair of tables,
+	// shortest first, or -1 for all of them. It must be set: 0 is an error,
+	// as for -paths and the paths of config files. Other than the shortest,
+	// routes are at most MaxHops foreign keys long (DefaultMaxHops if 0). A
+	// route is a sequence of tables, with a relationship for each
+	// combination of the foreign keys linking them along the way.
+	Paths   int
+	MaxHops int
+
+	// PathTables adds the tables that relationships go through as ghost
+	// tables, and replaces each relationship through them by its hops
+	PathTables bool
+
+	// Columns fetches the columns and keys of the tables
+	Columns bool
+
+	// Sort is the order of the tables: SortAlpha (the default), SortSchema
+	// or SortInput
+	Sort string
+}
+
+// Result is what Analyze finds: the tables, with their outgoing foreign keys,
+// and the relationships between them
+type Result struct {
+	Tables        []Table
+	Relationships []Relationship
+}
+
+// Analyze selects tables as options say, then finds the relationships between
+// them through the foreign keys of the whole database
+func Analyze(ctx context.Context, introspector Introspector, options Options) (*Result, error) {
+	if len(options.Tables) == 0 && options.TableRegex == "" {
+		return nil, errors.New("either Tables or TableRegex must be set")
+	}
+	schemas := options.Schemas
+	if len(schemas) == 0 {
+		schemas = []string{"public"}
+	}
+	depthDirection := options.DepthDirection
+	if depthDirection == "" {
+		depthDirection = "both"
+	}
+	if depthDirection != "both" && depthDirection != "out" && depthDirection != "in" {
+		return nil, fmt.Errorf("unknown depth direction %q", depthDirection)
+	}
+	maxPaths := options.Paths
+	if maxPaths == 0 || maxPaths < -1 {
+		return nil, fmt.Errorf("invalid number of paths %d (-1 for all routes)", maxPaths)
+	}
+	order := options.Sort
+	if order == "" {
+		order = SortAlpha
+	}
+	if order != SortAlpha && order != SortSchema && order != SortInput {
+		return nil, fmt.Errorf("unknown sort order %q", order)
+	}
+	maxHops := options.MaxHops
+	if maxHops == 0 {
+		maxHops = DefaultMaxHops
+	}
+	if maxHops < 0 {
+		return nil, fmt.Errorf("invalid maximum number of hops %d", maxHops)
+	}
+
+	// Get ALL foreign keys in the database to build complete graph
+	// (relationships may go through tables in other schemas)
+	allForeignKeys, err := introspector.AllForeignKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching all foreign keys: %w", err)
+	}
+
+	// Get list of tables matching the criteria
+	tables, err := introspector.MatchingTables(ctx, schemas, options.Tables, options.TableRegex)
+	if err != nil {
+		return nil, fmt.Errorf("fetching matching tables: %w", err)
+	}
+
+	if len(tables) == 0 {
+		return nil, ErrNoTables
+	}
+
+	// Extract qualified table names for filtering
+	qualifiedTableNames := make([]string, len(tables))
+	for i, t := range tables {
+		qualifiedTableNames[i] = getQualifiedName(t.Schema, t.Name)
+	}
+
+	// Grow the selection along foreign keys, whatever the schema
+	if options.Depth > 0 {
+		added := expandSelection(qualifiedTableNames, allForeignKeys, options.Depth, depthDirection, options.DepthLimit)
+		logger.Printf("Added %d tables within %d foreign keys of the selection: %s", len(added), options.Depth, strings.Join(added, ", "))
+		if len(added) > 0 {
+			addedTables, err := getTablesByName(ctx, introspector, schemas, added)
+			if err != nil {
+				return nil, fmt.Errorf("fetching added tables: %w", err)
+			}
+			tables = append(tables, addedTables...)
+			for _, t := range addedTables {
+				qualifiedTableNames = append(qualifiedTableNames, getQualifiedName(t.Schema, t.Name))
+			}
+		}
+	}
+
+	// The order of the tables decides that of the relationships, and which
+	// end of each relationship is which
+	sortTables(tables, order, options.Tables)
+	for i, t := range tables {
+		qualifiedTableNames[i] = getQualifiedName(t.Schema, t.Name)
+	}
+
+	// Get column info for all FK columns in one query
+	columnInfo, err := introspector.ColumnInfo(ctx, allForeignKeys)
+	if err != nil {
+		return nil, fmt.Errorf("fetching column info: %w", err)
+	}
+
+	relationships := calculateCardinalities(schemas, qualifiedTableNames, allForeignKeys, columnInfo, maxPaths, maxHops)
+
+	// Only the foreign keys followed need column info, so that those of
+	// tables the introspector knows nothing else about don't get in the way
+	for _, rel := range relationships {
+		for _, fk := range rel.ForeignKeys {
+			if _, ok := columnInfo[ColumnInfoKey(fk)]; !ok {
+				return nil, &ColumnInfoError{ForeignKey: fk}
+			}
+		}
+	}
+
+	// Replace the relationships through other tables by their hops, and
+	// add those tables as ghosts
+	ghostTables := make(map[string]bool)
+	if options.PathTables {
+		var pathTables []string
+		relationships, pathTables = expandPathTables(relationships, columnInfo, schemas[0])
+		logger.Printf("Added %d tables on the paths of relationships: %s", len(pathTables), strings.Join(pathTables, ", "))
+		if len(pathTables) > 0 {
+			addedTables, err := getTablesByName(ctx, introspector, schemas, pathTables)
+			if err != nil {
+				return nil, fmt.Errorf("fetching path tables: %w", err)
+			}
+			tables = append(tables, addedTables...)
+			for _, t := range addedTables {
+				qualifiedTableNames = append(qualifiedTableNames, getQualifiedName(t.Schema, t.Name))
+				ghostTables[t.Schema+"."+t.Name] = true
+			}
+		}
+	}
+
+	if options.Columns {
+		// Filter foreign keys for selected tables (for column display)
+		selectedForeignKeys := filterForeignKeys(allForeignKeys, qualifiedTableNames)
+		tables, err = introspector.TableColumns(ctx, tables, selectedForeignKeys)
+		if err != nil {
+			return nil, fmt.Errorf("fetching table columns: %w", err)
+		}
+	}
+	attachForeignKeys(tables, allForeignKeys)
+	for i := range tables {
+		tables[i].Ghost = ghostTables[tables[i].Schema+"."+tables[i].Name]
+	}
+	sortTables(tables, order, options.Tables)
+
+	return &Result{Tables: tables, Relationships: relationships}, nil
+}
+
+func filterForeignKeys(allForeignKeys []ForeignKey, tables []string) []ForeignKey {
+	tableSet := make(map[string]bool)
+	for _, table := range tables {
+		tableSet[table] = true
+	}
+
+	var filtered []ForeignKey
+	for _, fk := range allForeignKeys {
+		fromQualified := getQualifiedName(fk.FromSchema, fk.FromTable)
+		toQualified := getQualifiedName(fk.ToSchema, fk.ToTable)
+		if tableSet[fromQualified] && tableSet[toQualified] {
+			filtered = append(filtered, fk)
+		}
+	}
+
+	logger.Printf("Filtered %d foreign keys for selected tables (from %d total)", len(filtered), len(allForeignKeys))
+	return filtered
+}
+
+// getTablesByName fetches tables given their qualified names, as if they had
+// been named in Options.Tables, in one call to the introspector
+func getTablesByName(ctx context.Context, introspector Introspector, schemas []string, qualifiedNames []string) ([]Table, error) {
+	// Schema-qualified even in public, so that schemas doesn't apply
+	tableNames := make([]string, len(qualifiedNames))
+	for i, name := range qualifiedNames {
+		schema, table := parseQualifiedName(name)
+		tableNames[i] = schema + "." + table
+	}
+	return introspector.MatchingTables(ctx, schemas, tableNames, "")
+}
+
+// attachForeignKeys sets the outgoing foreign keys of each table
+func attachForeignKeys(tables []Table, allForeignKeys []ForeignKey) {
+	index := make(map[string]int, len(tables))
+	for i, table := range tables {
+		index[table.Schema+"."+table.Name] = i
+	}
+	for _, fk := range allForeignKeys {
+		if i, ok := index[fk.FromSchema+"."+fk.FromTable]; ok {
+			tables[i].ForeignKeys = append(tables[i].ForeignKeys, fk)
+		}
+	}
+}
+
+// expandPathTables replaces each relationship through tables outside the
+// selection by a relationship for each foreign key along its path, returning
+// them with the tables on the paths. Foreign keys on several paths, or that
+// are relationships of their own, are only drawn once.
+func expandPathTables(relationships []Relationship, columnInfo map[string]ColumnInfo, schema string) ([]Relationship, []string) {
+	seenHops := make(map[string]bool)
+	hopKey := func(fk ForeignKey) string {
+		return fk.FromSchema + "." + fk.FromTable + "." + fk.ConstraintName
+	}
+	for _, rel := range relationships {
+		if len(rel.Path) <= 2 {
+			for _, fk := range rel.ForeignKeys {
+				seenHops[hopKey(fk)] = true
+			}
+		}
+	}
+
+	var expanded []Relationship
+	var pathTables []string
+	seenTables := make(map[string]bool)
+	for _, rel := range relationships {
+		if len(rel.Path) <= 2 {
+			expanded = append(expanded, rel)
+			continue
+		}
+
+		for _, table := range rel.Path[1 : len(rel.Path)-1] {
+			if !seenTables[table] {
+				seenTables[table] = true
+				pathTables = append(pathTables, table)
+			}
+		}
+
+		for i, fk := range rel.ForeignKeys {
+			if seenHops[hopKey(fk)] {
+				continue
+			}
+			seenHops[hopKey(fk)] = true
+
+			hopPath := []string{rel.Path[i], rel.Path[i+1]}
+			hop := calculatePathCardinality(hopPath, []ForeignKey{fk}, columnInfo, schema)
+			if hop == nil {
+				continue
+			}
+			hop.Path = hopPath
+			hop.ForeignKeys = []ForeignKey{fk}
+			hop.Label = getRelationshipLabel(hopPath, hop.ForeignKeys, false)
+			expanded = append(expanded, *hop)
+		}
+	}
+
+	return expanded, pathTables
+}
+
+// expandSelection returns the tables within depth foreign keys of the selected
+// ones, following foreign keys out (to the tables they reference), in (from
+// the tables referencing them) or both. Unless limit is 0, at most limit
+// tables are added, the closest first.
+func expandSelection(selectedTables []string, allForeignKeys []ForeignKey, depth int, direction string, limit int) []string {
+	g, tableToNode, nodeToTable := buildForeignKeyGraph(allForeignKeys)
+
+	seen := make(map[string]bool)
+	for _, table := range selectedTables {
+		seen[table] = true
+	}
+
+	// Breadth-first, one level of foreign keys at a time
+	var added []string
+	frontier := selectedTables
+	for level := 1; level <= depth && len(frontier) > 0; level++ {
+		var next []string
+		for _, table := range frontier {
+			node, ok := tableToNode[table]
+			if !ok {
+				continue // No foreign keys
+			}
+			// Edges go from the referenced table to the referencing one
+			var neighbours []graph.Node
+			if direction != "in" {
+				neighbours = append(neighbours, graph.NodesOf(g.To(node.ID()))...)
+			}
+			if direction != "out" {
+				neighbours = append(neighbours, graph.NodesOf(g.From(node.ID()))...)
+			}
+			for _, neighbour := range neighbours {
+				name := nodeToTable[neighbour.ID()]
+				if !seen[name] {
+					seen[name] = true
+					next = append(next, name)
+				}
+			}
+		}
+		sort.Strings(next)
+
+		if limit > 0 && len(added)+len(next) > limit {
+			logger.Printf("Reached the limit of %d added tables, skipping %d tables at depth %d", limit, len(added)+len(next)-limit, level)
+			added = append(added, next[:limit-len(added)]...)
+			break
+		}
+		added = append(added, next...)
+		frontier = next
+	}
+
+	return added
+}
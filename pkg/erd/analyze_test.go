@@ -0,0 +1,360 @@
+package erd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeColumnInfo(t *testing.T) {
+	// The foreign key of the typed table points has no column info, as the
+	// table is skipped
+	catalog := loadTestSchema(t, "partitions.sql")
+	if _, err := Analyze(context.Background(), catalog, Options{Tables: []string{"cities", "measurements"}, Paths: 1}); err != nil {
+		t.Errorf("Analyze of tables unrelated to points: %v", err)
+	}
+
+	// Foreign keys along a relationship do need column info
+	catalog = &Catalog{
+		Tables: []CatalogTable{
+			{Schema: "public", Name: "users", Columns: []CatalogColumn{{Name: "id", DataType: "integer", NotNull: true}}, PrimaryKey: []string{"id"}},
+			{Schema: "public", Name: "groups", Columns: []CatalogColumn{{Name: "id", DataType: "integer", NotNull: true}}, PrimaryKey: []string{"id"}},
+		},
+		ForeignKeys: []ForeignKey{
+			{FromSchema: "public", FromTable: "members", ToSchema: "public", ToTable: "users", Columns: []ColumnPair{{From: "user_id", To: "id"}}, ConstraintName: "members_user_id_fkey"},
+			{FromSchema: "public", FromTable: "members", ToSchema: "public", ToTable: "groups", Columns: []ColumnPair{{From: "group_id", To: "id"}}, ConstraintName: "members_group_id_fkey"},
+		},
+	}
+	_, err := Analyze(context.Background(), catalog, Options{Tables: []string{"users", "groups"}, Paths: 1})
+	var columnInfoErr *ColumnInfoError
+	if !errors.As(err, &columnInfoErr) {
+		t.Fatalf("Analyze through members: error = %v, want a ColumnInfoError", err)
+	}
+	if got := columnInfoErr.ForeignKey.FromTable; got != "members" {
+		t.Errorf("ColumnInfoError for a foreign key of %s, want members", got)
+	}
+}
+
+// testTable returns a table, in the public schema unless the name is
+// qualified, whose columns are NOT NULL unless their name ends with ?
+func testTable(qualifiedName string, primaryKey []string, columns ...string) CatalogTable {
+	schema, name := parseQualifiedName(qualifiedName)
+	table := CatalogTable{Schema: schema, Name: name, PrimaryKey: primaryKey}
+	for _, column := range columns {
+		nullable := strings.HasSuffix(column, "?")
+		table.Columns = append(table.Columns, CatalogColumn{
+			Name:     strings.TrimSuffix(column, "?"),
+			DataType: "integer",
+			NotNull:  !nullable,
+		})
+	}
+	return table
+}
+
+func testForeignKey(name, from string, fromColumns []string, to string, toColumns []string) ForeignKey {
+	fk := ForeignKey{ConstraintName: name}
+	fk.FromSchema, fk.FromTable = parseQualifiedName(from)
+	fk.ToSchema, fk.ToTable = parseQualifiedName(to)
+	for i := range fromColumns {
+		fk.Columns = append(fk.Columns, ColumnPair{From: fromColumns[i], To: toColumns[i]})
+	}
+	return fk
+}
+
+func newTestCatalog() *Catalog {
+	id := []string{"id"}
+	catalog := &Catalog{
+		Tables: []CatalogTable{
+			testTable("users", id, "id"),
+			testTable("groups", id, "id"),
+			testTable("user_groups", []string{"user_id", "group_id"}, "user_id", "group_id"),
+			testTable("group_admins", nil, "user_id", "group_id"),
+			testTable("profiles", id, "id", "user_id"),
+			testTable("addresses", id, "id"),
+			testTable("orders", id, "id", "user_id", "billing_address_id", "shipping_address_id?"),
+			testTable("order_lines", []string{"order_id", "line"}, "order_id", "line", "product_id"),
+			testTable("products", id, "id"),
+			testTable("employees", id, "id", "manager_id?"),
+			testTable("accounts", []string{"tenant_id", "id"}, "tenant_id", "id"),
+			testTable("account_settings", []string{"tenant_id", "account_id"}, "tenant_id", "account_id"),
+			testTable("account_logins", []string{"tenant_id", "account_id", "at"}, "tenant_id", "account_id", "at"),
+			testTable("sales.invoices", id, "id", "order_id"),
+		},
+		ForeignKeys: []ForeignKey{
+			testForeignKey("user_groups_user_id_fkey", "user_groups", []string{"user_id"}, "users", id),
+			testForeignKey("user_groups_group_id_fkey", "user_groups", []string{"group_id"}, "groups", id),
+			testForeignKey("group_admins_user_id_fkey", "group_admins", []string{"user_id"}, "users", id),
+			testForeignKey("group_admins_group_id_fkey", "group_admins", []string{"group_id"}, "groups", id),
+			testForeignKey("profiles_user_id_fkey", "profiles", []string{"user_id"}, "users", id),
+			testForeignKey("orders_user_id_fkey", "orders", []string{"user_id"}, "users", id),
+			testForeignKey("orders_billing_address_id_fkey", "orders", []string{"billing_address_id"}, "addresses", id),
+			testForeignKey("orders_shipping_address_id_fkey", "orders", []string{"shipping_address_id"}, "addresses", id),
+			testForeignKey("order_lines_order_id_fkey", "order_lines", []string{"order_id"}, "orders", id),
+			testForeignKey("order_lines_product_id_fkey", "order_lines", []string{"product_id"}, "products", id),
+			testForeignKey("employees_manager_id_fkey", "employees", []string{"manager_id"}, "employees", id),
+			testForeignKey("account_settings_account_fkey", "account_settings", []string{"tenant_id", "account_id"}, "accounts", []string{"tenant_id", "id"}),
+			testForeignKey("account_logins_account_fkey", "account_logins", []string{"tenant_id", "account_id"}, "accounts", []string{"tenant_id", "id"}),
+			testForeignKey("invoices_order_id_fkey", "sales.invoices", []string{"order_id"}, "orders", id),
+		},
+	}
+	// Profiles are one-to-one with users
+	catalog.Tables[4].UniqueKeys = [][]string{{"user_id"}}
+	return catalog
+}
+
+// describeResult lists the tables of a result, and its relationships as in
+// Mermaid
+func describeResult(result *Result) ([]string, []string) {
+	var tables, relationships []string
+	for _, table := range result.Tables {
+		name := getQualifiedName(table.Schema, table.Name)
+		if table.Ghost {
+			name += " (ghost)"
+		}
+		tables = append(tables, name)
+	}
+	for _, rel := range result.Relationships {
+		relationships = append(relationships, fmt.Sprintf("%s %s %s : %s",
+			getQualifiedName(rel.From.Schema, rel.From.Name),
+			getMermaidRelationType(rel.FromCardinality, rel.ToCardinality),
+			getQualifiedName(rel.To.Schema, rel.To.Name),
+			rel.Label))
+	}
+	return tables, relationships
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       Options
+		tables        []string
+		relationships []string
+	}{
+		{
+			name:    "parallel foreign keys",
+			options: Options{Tables: []string{"orders", "addresses"}, Paths: 1},
+			tables:  []string{"addresses", "orders"},
+			relationships: []string{
+				"orders }|--|| addresses : billing_address_id",
+				"orders }o--|| addresses : shipping_address_id",
+			},
+		},
+		{
+			name:          "self-reference",
+			options:       Options{Tables: []string{"employees"}, Paths: 1},
+			tables:        []string{"employees"},
+			relationships: []string{"employees |o--o{ employees : manager_id"},
+		},
+		{
+			name:          "unique foreign key",
+			options:       Options{Tables: []string{"users", "profiles"}, Paths: 1},
+			tables:        []string{"profiles", "users"},
+			relationships: []string{"profiles ||--|| users : user_id"},
+		},
+		{
+			name:    "composite foreign keys",
+			options: Options{Tables: []string{"accounts", "account_settings", "account_logins"}, Paths: 1},
+			tables:  []string{"account_logins", "account_settings", "accounts"},
+			relationships: []string{
+				"account_logins }|--|| accounts : tenant_id, account_id",
+				"account_settings ||--|| accounts : tenant_id, account_id",
+			},
+		},
+		{
+			name:          "chain of foreign keys",
+			options:       Options{Tables: []string{"users", "order_lines"}, Paths: 1},
+			tables:        []string{"order_lines", "users"},
+			relationships: []string{"order_lines }|--|| users : via orders"},
+		},
+		{
+			name:    "chain through a nullable foreign key",
+			options: Options{Tables: []string{"order_lines", "addresses"}, Paths: 1},
+			tables:  []string{"addresses", "order_lines"},
+			relationships: []string{
+				"order_lines }|--|| addresses : via orders (order_id; billing_address_id)",
+				"order_lines }o--|| addresses : via orders (order_id; shipping_address_id)",
+			},
+		},
+		{
+			name:    "common descendant",
+			options: Options{Tables: []string{"users", "addresses"}, Paths: 1},
+			tables:  []string{"addresses", "users"},
+			relationships: []string{
+				"addresses }|--|{ users : via orders (billing_address_id; user_id)",
+				"addresses }|--o{ users : via orders (shipping_address_id; user_id)",
+			},
+		},
+		{
+			name:          "junction table",
+			options:       Options{Tables: []string{"users", "groups"}, Paths: 1},
+			tables:        []string{"groups", "users"},
+			relationships: []string{"groups }|--|{ users : via group_admins"},
+		},
+		{
+			name:    "all routes",
+			options: Options{Tables: []string{"users", "groups"}, Paths: -1},
+			tables:  []string{"groups", "users"},
+			relationships: []string{
+				"groups }|--|{ users : via group_admins",
+				"groups }|--|{ users : via user_groups",
+			},
+		},
+		{
+			name:          "shortest route longer than MaxHops",
+			options:       Options{Tables: []string{"users", "groups"}, Paths: 2, MaxHops: 1},
+			tables:        []string{"groups", "users"},
+			relationships: []string{"groups }|--|{ users : via group_admins"},
+		},
+		{
+			name:    "path tables",
+			options: Options{Tables: []string{"users", "order_lines"}, PathTables: true, Paths: 1},
+			tables:  []string{"order_lines", "orders (ghost)", "users"},
+			relationships: []string{
+				"order_lines }|--|| orders : order_id",
+				"orders }|--|| users : user_id",
+			},
+		},
+		{
+			name:    "depth out",
+			options: Options{Tables: []string{"order_lines"}, Depth: 1, DepthDirection: "out", Paths: 1},
+			tables:  []string{"order_lines", "orders", "products"},
+			// A selected table can be the common descendant of two others
+			relationships: []string{
+				"order_lines }|--|| orders : order_id",
+				"order_lines }|--|| products : product_id",
+				"orders }|--|{ products : via order_lines",
+			},
+		},
+		{
+			name:    "depth in with limit",
+			options: Options{Tables: []string{"users"}, Depth: 2, DepthDirection: "in", DepthLimit: 5, Paths: 1},
+			tables:  []string{"group_admins", "order_lines", "orders", "profiles", "user_groups", "users"},
+			relationships: []string{
+				"group_admins }|--|| users : user_id",
+				"order_lines }|--|| orders : order_id",
+				"orders }|--|| users : user_id",
+				"profiles ||--|| users : user_id",
+				"user_groups }|--|| users : user_id",
+			},
+		},
+		{
+			name:    "sort by schema",
+			options: Options{Schemas: []string{"public", "sales"}, Tables: []string{"invoices", "users", "orders"}, Sort: SortSchema, Paths: 1},
+			tables:  []string{"orders", "users", "sales.invoices"},
+			relationships: []string{
+				"orders }|--|| users : user_id",
+				"sales.invoices }|--|| orders : order_id",
+			},
+		},
+		{
+			name:    "sort as input",
+			options: Options{Schemas: []string{"public", "sales"}, Tables: []string{"users", "invoices", "orders"}, Sort: SortInput, Paths: 1},
+			tables:  []string{"users", "sales.invoices", "orders"},
+			// Not sales.invoices to users through orders, as it is selected
+			relationships: []string{
+				"orders }|--|| users : user_id",
+				"sales.invoices }|--|| orders : order_id",
+			},
+		},
+		{
+			name:    "sort as input through a common descendant",
+			options: Options{Tables: []string{"users", "addresses"}, Sort: SortInput, Paths: 1},
+			tables:  []string{"users", "addresses"},
+			relationships: []string{
+				"users }|--|{ addresses : via orders (user_id; billing_address_id)",
+				"users }o--|{ addresses : via orders (user_id; shipping_address_id)",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Analyze(context.Background(), newTestCatalog(), test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tables, relationships := describeResult(result)
+			if !reflect.DeepEqual(tables, test.tables) {
+				t.Errorf("tables = %q, want %q", tables, test.tables)
+			}
+			if !reflect.DeepEqual(relationships, test.relationships) {
+				t.Errorf("relationships = %q, want %q", relationships, test.relationships)
+			}
+		})
+	}
+}
+
+func TestAnalyzeCardinality(t *testing.T) {
+	// Each profile belongs to one user and each user has at most one profile,
+	// and the same down to avatar files, and from memberships to users and
+	// accounts; a user has any number of sessions, and a profile at most one
+	// banner, which may belong to no profile, as a device to a user
+	id := []string{"id"}
+	catalog := &Catalog{
+		Tables: []CatalogTable{
+			testTable("users", id, "id"),
+			testTable("profiles", id, "id", "user_id"),
+			testTable("avatars", id, "id", "profile_id"),
+			testTable("sessions", id, "id", "user_id"),
+			testTable("avatar_files", id, "id", "avatar_id"),
+			testTable("banners", id, "id", "profile_id?"),
+			testTable("accounts", id, "id"),
+			testTable("memberships", id, "id", "user_id", "account_id"),
+			testTable("devices", id, "id"),
+			testTable("device_owners", id, "id", "device_id", "user_id?"),
+		},
+		ForeignKeys: []ForeignKey{
+			testForeignKey("profiles_user_id_fkey", "profiles", []string{"user_id"}, "users", id),
+			testForeignKey("avatars_profile_id_fkey", "avatars", []string{"profile_id"}, "profiles", id),
+			testForeignKey("sessions_user_id_fkey", "sessions", []string{"user_id"}, "users", id),
+			testForeignKey("avatar_files_avatar_id_fkey", "avatar_files", []string{"avatar_id"}, "avatars", id),
+			testForeignKey("banners_profile_id_fkey", "banners", []string{"profile_id"}, "profiles", id),
+			testForeignKey("memberships_user_id_fkey", "memberships", []string{"user_id"}, "users", id),
+			testForeignKey("memberships_account_id_fkey", "memberships", []string{"account_id"}, "accounts", id),
+			testForeignKey("device_owners_device_id_fkey", "device_owners", []string{"device_id"}, "devices", id),
+			testForeignKey("device_owners_user_id_fkey", "device_owners", []string{"user_id"}, "users", id),
+		},
+	}
+	catalog.Tables[1].UniqueKeys = [][]string{{"user_id"}}
+	catalog.Tables[2].UniqueKeys = [][]string{{"profile_id"}}
+	catalog.Tables[4].UniqueKeys = [][]string{{"avatar_id"}}
+	catalog.Tables[5].UniqueKeys = [][]string{{"profile_id"}}
+	catalog.Tables[7].UniqueKeys = [][]string{{"user_id"}, {"account_id"}}
+	catalog.Tables[9].UniqueKeys = [][]string{{"device_id"}, {"user_id"}}
+
+	tests := []struct {
+		tables       []string
+		relationship string
+	}{
+		{[]string{"sessions", "users"}, "sessions }|--|| users : user_id"},
+		{[]string{"profiles", "users"}, "profiles ||--|| users : user_id"},
+		{[]string{"avatars", "users"}, "avatars ||--|| users : via profiles"},
+		{[]string{"avatar_files", "users"}, "avatar_files ||--|| users : via avatars, profiles"},
+		{[]string{"banners", "users"}, "banners |o--|| users : via profiles"},
+		{[]string{"accounts", "users"}, "accounts ||--|| users : via memberships"},
+		{[]string{"devices", "users"}, "devices |o--|| users : via device_owners"},
+	}
+	for _, test := range tests {
+		result, err := Analyze(context.Background(), catalog, Options{Tables: test.tables, Paths: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, relationships := describeResult(result)
+		if want := []string{test.relationship}; !reflect.DeepEqual(relationships, want) {
+			t.Errorf("relationships of %v = %q, want %q", test.tables, relationships, want)
+		}
+	}
+}
+
+func TestAnalyzeInvalidPaths(t *testing.T) {
+	// Paths isn't defaulted: 0 is as invalid as less than -1
+	for _, paths := range []int{0, -2} {
+		_, err := Analyze(context.Background(), newTestCatalog(), Options{Tables: []string{"users"}, Paths: paths})
+		if err == nil {
+			t.Errorf("Paths %d: no error", paths)
+		}
+	}
+}
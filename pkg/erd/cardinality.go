@@ -0,0 +1,116 @@
+package erd
+
+func calculatePathCardinality(pathTables []string, fks []ForeignKey, columnInfo map[string]ColumnInfo, schema string) *Relationship {
+	if len(pathTables) < 2 || len(fks) != len(pathTables)-1 {
+		return nil
+	}
+
+	// Walk the path hop by hop, composing the cardinality seen at each end
+	var fromCard, toCard Cardinality
+	for i, fk := range fks {
+		hop := calculateHopCardinality(pathTables[i], pathTables[i+1], fk, columnInfo, schema)
+		if i == 0 {
+			fromCard = hop.FromCardinality
+			toCard = hop.ToCardinality
+			continue
+		}
+		fromCard = combineCardinality(fromCard, hop.FromCardinality)
+		toCard = combineCardinality(toCard, hop.ToCardinality)
+	}
+
+	fromSchema, fromName := parseQualifiedName(pathTables[0])
+	toSchema, toName := parseQualifiedName(pathTables[len(pathTables)-1])
+
+	return &Relationship{
+		From:            Table{Name: fromName, Schema: fromSchema},
+		To:              Table{Name: toName, Schema: toSchema},
+		FromCardinality: fromCard,
+		ToCardinality:   toCard,
+	}
+}
+
+func calculateHopCardinality(fromTable, toTable string, fk ForeignKey, columnInfo map[string]ColumnInfo, schema string) *Relationship {
+	if getQualifiedName(fk.FromSchema, fk.FromTable) == fromTable {
+		return calculateDirectCardinality(fromTable, toTable, fk, columnInfo, schema)
+	}
+
+	// The FK points back along the path: swap the tables to get the correct direction
+	rel := calculateDirectCardinality(toTable, fromTable, fk, columnInfo, schema)
+	// Swap the relationship direction
+	return &Relationship{
+		From:            rel.To,
+		To:              rel.From,
+		FromCardinality: rel.ToCardinality,
+		ToCardinality:   rel.FromCardinality,
+	}
+}
+
+// combineCardinality composes two cardinalities met in sequence along a path:
+// the result is mandatory only if both are, and single-valued only if both are.
+func combineCardinality(a, b Cardinality) Cardinality {
+	combined := Cardinality{Min: "0", Max: "*"}
+	if a.Min == "1" && b.Min == "1" {
+		combined.Min = "1"
+	}
+	if a.Max == "1" && b.Max == "1" {
+		combined.Max = "1"
+	}
+	return combined
+}
+
+func calculateDirectCardinality(fromTable, toTable string, fk ForeignKey, columnInfo map[string]ColumnInfo, schema string) *Relationship {
+	info, found := columnInfo[ColumnInfoKey(fk)]
+
+	min := "0"
+	max := "*"
+
+	if found {
+		if !info.IsNullable {
+			min = "1"
+		}
+		if info.HasUniqueConstraint {
+			max = "1"
+		}
+	}
+
+	// Parse schema from qualified table names
+	fromSchema, fromName := parseQualifiedName(fromTable)
+	toSchema, toName := parseQualifiedName(toTable)
+
+	return &Relationship{
+		From:            Table{Name: fromName, Schema: fromSchema},
+		To:              Table{Name: toName, Schema: toSchema},
+		FromCardinality: Cardinality{Min: min, Max: max},
+		ToCardinality:   Cardinality{Min: "1", Max: "1"},
+	}
+}
+
+// calculateSelfCardinality describes a foreign key from a table to itself as a
+// hierarchy: the From end counts parent rows and the To end counts child rows
+func calculateSelfCardinality(table string, fk ForeignKey, columnInfo map[string]ColumnInfo) Relationship {
+	parentCard := Cardinality{Min: "0", Max: "1"}
+	childCard := Cardinality{Min: "0", Max: "*"}
+
+	if info, found := columnInfo[ColumnInfoKey(fk)]; found {
+		if !info.IsNullable {
+			parentCard.Min = "1"
+		}
+		if info.HasUniqueConstraint {
+			childCard.Max = "1"
+		}
+	}
+
+	schema, name := parseQualifiedName(table)
+	path := []string{table, table}
+	fks := []ForeignKey{fk}
+
+	return Relationship{
+		From:            Table{Name: name, Schema: schema},
+		To:              Table{Name: name, Schema: schema},
+		FromCardinality: parentCard,
+		ToCardinality:   childCard,
+		Path:            path,
+		ForeignKeys:     fks,
+		Label:           getRelationshipLabel(path, fks, false),
+	}
+}
@@ -0,0 +1,206 @@
+package erd
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Catalog is an in-memory Introspector, for when there is no database to
+// query: it is built from a pg_dump schema file, or directly in tests.
+type Catalog struct {
+	Tables      []CatalogTable
+	ForeignKeys []ForeignKey
+}
+
+type CatalogTable struct {
+	Schema     string
+	Name       string
+	Comment    string
+	Columns    []CatalogColumn
+	PrimaryKey []string
+	UniqueKeys [][]string // UNIQUE constraints and unique indexes, excluding partial ones
+}
+
+type CatalogColumn struct {
+	Name     string
+	DataType string // As declared, including any type modifiers
+	NotNull  bool
+	Default  string // Default expression, empty if none
+	Comment  string
+}
+
+func (c *Catalog) tableIndex() map[string]*CatalogTable {
+	index := make(map[string]*CatalogTable, len(c.Tables))
+	for i := range c.Tables {
+		index[c.Tables[i].Schema+"."+c.Tables[i].Name] = &c.Tables[i]
+	}
+	return index
+}
+
+func (c *Catalog) AllForeignKeys(ctx context.Context) ([]ForeignKey, error) {
+	return c.ForeignKeys, nil
+}
+
+func (c *Catalog) MatchingTables(ctx context.Context, schemas []string, tableNames []string, tableRegex string) ([]Table, error) {
+	start := time.Now()
+	tableMap := make(map[string]Table) // Use map to deduplicate
+
+	schemaSet := make(map[string]bool)
+	for _, schema := range schemas {
+		schemaSet[schema] = true
+	}
+
+	// Handle exact table names from -tables option
+	for _, tableName := range tableNames {
+		schema, name := "", tableName
+		// Check if table name is schema-qualified
+		if strings.Contains(tableName, ".") {
+			parts := strings.SplitN(tableName, ".", 2)
+			schema, name = parts[0], parts[1]
+		}
+		for _, t := range c.Tables {
+			if t.Name != name || (schema != "" && t.Schema != schema) || (schema == "" && !schemaSet[t.Schema]) {
+				continue
+			}
+			tableMap[t.Schema+"."+t.Name] = Table{Name: t.Name, Schema: t.Schema, Comment: t.Comment}
+		}
+	}
+
+	// Handle regex pattern from -table-regex option
+	if tableRegex != "" {
+		re, err := regexp.Compile(tableRegex)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range c.Tables {
+			if schemaSet[t.Schema] && re.MatchString(t.Name) {
+				tableMap[t.Schema+"."+t.Name] = Table{Name: t.Name, Schema: t.Schema, Comment: t.Comment}
+			}
+		}
+	}
+
+	tables := sortedTables(tableMap)
+	logger.Printf("Found %d tables matching criteria (took %v)", len(tables), time.Since(start))
+	return tables, nil
+}
+
+func (c *Catalog) ColumnInfo(ctx context.Context, foreignKeys []ForeignKey) (map[string]ColumnInfo, error) {
+	index := c.tableIndex()
+	columnInfo := make(map[string]ColumnInfo)
+
+	for _, fk := range foreignKeys {
+		table, ok := index[fk.FromSchema+"."+fk.FromTable]
+		if !ok {
+			continue
+		}
+
+		fkColumns := make(map[string]bool)
+		for _, column := range fk.FromColumns() {
+			fkColumns[column] = true
+		}
+
+		var info ColumnInfo
+
+		// Primary key columns are implicitly NOT NULL
+		pkColumns := make(map[string]bool)
+		for _, column := range table.PrimaryKey {
+			pkColumns[column] = true
+		}
+		for _, column := range table.Columns {
+			if fkColumns[column.Name] && !column.NotNull && !pkColumns[column.Name] {
+				info.IsNullable = true
+			}
+		}
+
+		// Unique if some key has only columns of the FK
+		keys := table.UniqueKeys
+		if len(table.PrimaryKey) > 0 {
+			keys = append([][]string{table.PrimaryKey}, keys...)
+		}
+		for _, key := range keys {
+			covered := len(key) > 0
+			for _, column := range key {
+				covered = covered && fkColumns[column]
+			}
+			if covered {
+				info.HasUniqueConstraint = true
+				break
+			}
+		}
+
+		columnInfo[ColumnInfoKey(fk)] = info
+	}
+
+	return columnInfo, nil
+}
+
+func (c *Catalog) TableColumns(ctx context.Context, tables []Table, foreignKeys []ForeignKey) ([]Table, error) {
+	index := c.tableIndex()
+
+	// Create FK lookup map using qualified names
+	fkLookup := make(map[string]bool)
+	for _, fk := range foreignKeys {
+		qualifiedName := getQualifiedName(fk.FromSchema, fk.FromTable)
+		for _, column := range fk.FromColumns() {
+			fkLookup[qualifiedName+"."+column] = true
+		}
+	}
+
+	var result []Table
+	for _, t := range tables {
+		table, ok := index[t.Schema+"."+t.Name]
+		if !ok {
+			continue
+		}
+
+		pkColumns := make(map[string]bool)
+		for _, column := range table.PrimaryKey {
+			pkColumns[column] = true
+		}
+
+		qualifiedTableName := getQualifiedName(table.Schema, table.Name)
+		columns := make([]Column, 0, len(table.Columns))
+		for _, column := range table.Columns {
+			columns = append(columns, Column{
+				Name:       column.Name,
+				DataType:   baseDataType(column.DataType),
+				FullType:   column.DataType,
+				IsPK:       pkColumns[column.Name],
+				IsFK:       fkLookup[qualifiedTableName+"."+column.Name],
+				IsNullable: !column.NotNull && !pkColumns[column.Name],
+				Default:    column.Default,
+				Comment:    column.Comment,
+			})
+		}
+		result = append(result, Table{
+			Name:       table.Name,
+			Schema:     table.Schema,
+			Comment:    table.Comment,
+			Columns:    columns,
+			UniqueKeys: table.UniqueKeys,
+		})
+		markUniqueColumns(&result[len(result)-1])
+	}
+
+	return result, nil
+}
+
+// baseDataType strips type modifiers such as the length of varchar(255), to
+// match what format_type reports without a typmod
+func baseDataType(declared string) string {
+	var sb strings.Builder
+	depth := 0
+	for _, r := range declared {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case depth == 0:
+			sb.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
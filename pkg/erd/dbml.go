@@ -0,0 +1,222 @@
+package erd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var dbmlPlainName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// dbmlRef is a relationship as DBML sees it: a reference between columns
+type dbmlRef struct {
+	fromTable   string
+	fromColumns []string
+	toTable     string
+	toColumns   []string
+	op          string
+}
+
+// generateDBMLDiagram writes the tables and relationships in the DBML format
+// of dbdiagram.io and dbdocs. Direct single-column foreign keys become column
+// refs, other relationships Ref lines, and relationships that can't be
+// expressed as a reference between columns become table notes.
+func generateDBMLDiagram(tables []Table, relationships []Relationship, options RenderOptions) (string, error) {
+	var sb strings.Builder
+
+	// Add comments at the top
+	sb.WriteString("// Generated by https://github.com/Dirac-Software/ersummary\n")
+	sb.WriteString(fmt.Sprintf("// Command: %s\n", options.CommandLine))
+
+	columnRefs := make(map[string]string) // table.column -> ref setting
+	notes := make(map[string][]string)    // table -> notes
+	var refLines []string
+	seenRefs := make(map[string]bool)
+
+	for _, rel := range relationships {
+		ref, ok := getDBMLRef(rel)
+		if !ok {
+			fromTable := getDBMLTableName(rel.From)
+			notes[fromTable] = append(notes[fromTable], fmt.Sprintf("%s %s %s (%s)",
+				fromTable, getMermaidRelationType(rel.FromCardinality, rel.ToCardinality), getDBMLTableName(rel.To), rel.Label))
+			continue
+		}
+
+		// A direct single-column foreign key is annotated on its column
+		if len(rel.Path) == 2 && len(ref.fromColumns) == 1 {
+			key := ref.fromTable + "." + ref.fromColumns[0]
+			if _, exists := columnRefs[key]; !exists {
+				columnRefs[key] = fmt.Sprintf("ref: %s %s.%s", ref.op, ref.toTable, getDBMLName(ref.toColumns[0]))
+				continue
+			}
+		}
+
+		line := fmt.Sprintf("Ref: %s %s %s",
+			getDBMLColumnRef(ref.fromTable, ref.fromColumns),
+			ref.op,
+			getDBMLColumnRef(ref.toTable, ref.toColumns))
+		// DBML rejects two references with the same endpoints
+		if seenRefs[line] {
+			notes[ref.fromTable] = append(notes[ref.fromTable], fmt.Sprintf("%s %s %s (%s)",
+				ref.fromTable, getMermaidRelationType(rel.FromCardinality, rel.ToCardinality), ref.toTable, rel.Label))
+			continue
+		}
+		seenRefs[line] = true
+		if len(rel.Path) > 2 {
+			line += " // " + rel.Label
+		}
+		refLines = append(refLines, line)
+	}
+
+	for _, table := range tables {
+		tableName := getDBMLTableName(table)
+		if table.Ghost {
+			sb.WriteString(fmt.Sprintf("\nTable %s [headercolor: #AAAAAA] {\n", tableName))
+		} else {
+			sb.WriteString(fmt.Sprintf("\nTable %s {\n", tableName))
+		}
+		for _, col := range table.Columns {
+			var settings []string
+			if col.IsPK {
+				settings = append(settings, "pk")
+			} else {
+				// Implied by pk
+				if col.IsUnique {
+					settings = append(settings, "unique")
+				}
+				if !col.IsNullable {
+					settings = append(settings, "not null")
+				}
+			}
+			if col.Default != "" {
+				settings = append(settings, fmt.Sprintf("default: `%s`", col.Default))
+			}
+			if ref, ok := columnRefs[tableName+"."+getDBMLName(col.Name)]; ok {
+				settings = append(settings, ref)
+			}
+			if col.Comment != "" {
+				settings = append(settings, fmt.Sprintf("note: '%s'", getDBMLString(strings.Join(strings.Fields(col.Comment), " "))))
+			}
+			line := fmt.Sprintf("    %s %s", getDBMLName(col.Name), getDBMLName(getColumnType(col, options)))
+			if len(settings) > 0 {
+				line += fmt.Sprintf(" [%s]", strings.Join(settings, ", "))
+			}
+			sb.WriteString(line + "\n")
+		}
+		// The table's comment, then the relationships DBML can't express
+		var noteLines []string
+		if table.Ghost {
+			noteLines = append(noteLines, "Not selected: on the path of relationships between selected tables")
+		}
+		if table.Comment != "" {
+			noteLines = append(noteLines, strings.Split(table.Comment, "\n")...)
+		}
+		if tableNotes := notes[tableName]; len(tableNotes) > 0 {
+			if len(noteLines) > 0 {
+				noteLines = append(noteLines, "")
+			}
+			noteLines = append(noteLines, "Related tables:")
+			noteLines = append(noteLines, tableNotes...)
+		}
+		if len(noteLines) > 0 {
+			sb.WriteString(fmt.Sprintf("\n    Note: '''\n    %s\n    '''\n", getDBMLString(strings.Join(noteLines, "\n    "))))
+		}
+		sb.WriteString("}\n")
+	}
+
+	if len(refLines) > 0 {
+		sb.WriteString("\n")
+	}
+	for _, line := range refLines {
+		sb.WriteString(line + "\n")
+	}
+
+	return sb.String(), nil
+}
+
+// getDBMLRef works out the columns a relationship connects: those of the
+// foreign key for a direct relationship, or those used by the first and last
+// hops of the path for an indirect one
+func getDBMLRef(rel Relationship) (dbmlRef, bool) {
+	if len(rel.ForeignKeys) == 0 || len(rel.ForeignKeys) != len(rel.Path)-1 {
+		return dbmlRef{}, false
+	}
+
+	first := rel.ForeignKeys[0]
+	last := rel.ForeignKeys[len(rel.ForeignKeys)-1]
+	ref := dbmlRef{
+		fromTable: getDBMLTableName(rel.From),
+		toTable:   getDBMLTableName(rel.To),
+		op:        getDBMLRelationOp(rel.FromCardinality.Max == "*", rel.ToCardinality.Max == "*"),
+	}
+
+	// A self-reference is drawn parent to children, but refs go from the
+	// referencing columns
+	if rel.Path[0] == rel.Path[1] {
+		ref.fromColumns = getDBMLNames(first.FromColumns())
+		ref.toColumns = getDBMLNames(first.ToColumns())
+		ref.op = getDBMLRelationOp(rel.ToCardinality.Max == "*", rel.FromCardinality.Max == "*")
+		return ref, true
+	}
+
+	if getQualifiedName(first.FromSchema, first.FromTable) == rel.Path[0] {
+		ref.fromColumns = getDBMLNames(first.FromColumns())
+	} else {
+		ref.fromColumns = getDBMLNames(first.ToColumns())
+	}
+	if getQualifiedName(last.FromSchema, last.FromTable) == rel.Path[len(rel.Path)-1] {
+		ref.toColumns = getDBMLNames(last.FromColumns())
+	} else {
+		ref.toColumns = getDBMLNames(last.ToColumns())
+	}
+
+	return ref, len(ref.fromColumns) == len(ref.toColumns)
+}
+
+func getDBMLRelationOp(manyFrom, manyTo bool) string {
+	switch {
+	case manyFrom && manyTo:
+		return "<>"
+	case manyFrom:
+		return ">"
+	case manyTo:
+		return "<"
+	default:
+		return "-"
+	}
+}
+
+func getDBMLTableName(table Table) string {
+	if table.Schema == "public" || table.Schema == "" {
+		return getDBMLName(table.Name)
+	}
+	return getDBMLName(table.Schema) + "." + getDBMLName(table.Name)
+}
+
+func getDBMLColumnRef(table string, columns []string) string {
+	if len(columns) == 1 {
+		return table + "." + columns[0]
+	}
+	return fmt.Sprintf("%s.(%s)", table, strings.Join(columns, ", "))
+}
+
+// getDBMLString escapes text for a quoted DBML string or note
+func getDBMLString(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\\", "\\\\"), "'", "\\'")
+}
+
+// getDBMLName quotes names (and types) that aren't plain identifiers
+func getDBMLName(name string) string {
+	if dbmlPlainName.MatchString(name) {
+		return name
+	}
+	return "\"" + strings.ReplaceAll(name, "\"", "\\\"") + "\""
+}
+
+func getDBMLNames(names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = getDBMLName(name)
+	}
+	return quoted
+}
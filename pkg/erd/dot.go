@@ -0,0 +1,106 @@
+package erd
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+func generateDotDiagram(tables []Table, relationships []Relationship, options RenderOptions) (string, error) {
+	var sb strings.Builder
+
+	// Add comments at the top
+	sb.WriteString("// Generated by https://github.com/Dirac-Software/ersummary\n")
+	sb.WriteString(fmt.Sprintf("// Command: %s\n", options.CommandLine))
+	sb.WriteString("\ndigraph erd {\n")
+	sb.WriteString("    graph [rankdir=LR, fontname=\"Helvetica\"];\n")
+	sb.WriteString("    node [shape=plain, fontname=\"Helvetica\", fontsize=10];\n")
+	sb.WriteString("    edge [dir=both, fontname=\"Helvetica\", fontsize=9];\n\n")
+
+	ghosts := getGhostTables(tables)
+	for _, table := range tables {
+		qualifiedName := getQualifiedName(table.Schema, table.Name)
+		if table.Ghost {
+			sb.WriteString(fmt.Sprintf("    %s [shape=box, style=dashed, color=\"#888888\", fontcolor=\"#888888\", label=%s];\n",
+				getDotID(qualifiedName), getDotID(qualifiedName)))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("    %s [label=<\n", getDotID(qualifiedName)))
+		sb.WriteString("        <table border=\"0\" cellborder=\"1\" cellspacing=\"0\" cellpadding=\"4\">\n")
+		// Column comments get a cell of their own if there are any
+		cells := 3
+		for _, col := range table.Columns {
+			if col.Comment != "" {
+				cells = 4
+			}
+		}
+		sb.WriteString(fmt.Sprintf("        <tr><td colspan=\"%d\" bgcolor=\"#e0e0e0\"><b>%s</b></td></tr>\n", cells, html.EscapeString(qualifiedName)))
+		if table.Comment != "" {
+			sb.WriteString(fmt.Sprintf("        <tr><td colspan=\"%d\" align=\"left\"><i>%s</i></td></tr>\n", cells, getDotText(table.Comment)))
+		}
+		for _, col := range table.Columns {
+			row := fmt.Sprintf("<td align=\"left\">%s</td><td align=\"left\">%s</td><td>%s</td>",
+				html.EscapeString(col.Name),
+				html.EscapeString(getColumnType(col, options)),
+				getKeyIndicator(col))
+			switch {
+			case col.Comment != "":
+				row += fmt.Sprintf("<td align=\"left\"><i>%s</i></td>", getDotText(col.Comment))
+			case cells == 4:
+				row += "<td></td>"
+			}
+			sb.WriteString(fmt.Sprintf("        <tr>%s</tr>\n", row))
+		}
+		sb.WriteString("        </table>\n")
+		sb.WriteString("    >];\n")
+	}
+
+	if len(relationships) > 0 {
+		sb.WriteString("\n")
+	}
+	for _, rel := range relationships {
+		attributes := []string{
+			fmt.Sprintf("arrowtail=%s", getDotArrow(rel.FromCardinality)),
+			fmt.Sprintf("arrowhead=%s", getDotArrow(rel.ToCardinality)),
+		}
+		if rel.Label != "" {
+			attributes = append(attributes, fmt.Sprintf("label=%s", getDotID(rel.Label)))
+		}
+		// Relationships through tables outside the diagram are dashed, as are
+		// the foreign keys of ghost tables
+		if len(rel.Path) > 2 || isGhostRelationship(rel, ghosts) {
+			attributes = append(attributes, "style=dashed")
+		}
+		sb.WriteString(fmt.Sprintf("    %s -> %s [%s];\n",
+			getDotID(getQualifiedName(rel.From.Schema, rel.From.Name)),
+			getDotID(getQualifiedName(rel.To.Schema, rel.To.Name)),
+			strings.Join(attributes, ", ")))
+	}
+
+	sb.WriteString("}\n")
+	return sb.String(), nil
+}
+
+// getDotID quotes a string for use as a Graphviz ID
+func getDotID(s string) string {
+	return "\"" + strings.ReplaceAll(strings.ReplaceAll(s, "\\", "\\\\"), "\"", "\\\"") + "\""
+}
+
+// getDotText escapes text for an HTML-like label, keeping its line breaks
+func getDotText(s string) string {
+	return strings.ReplaceAll(html.EscapeString(s), "\n", "<br align=\"left\"/>")
+}
+
+// getDotArrow draws a cardinality as a crow's foot arrow: the shape next to
+// the table shows the maximum, the one after it the minimum
+func getDotArrow(card Cardinality) string {
+	max := "tee"
+	if card.Max == "*" {
+		max = "crow"
+	}
+	min := "tee"
+	if card.Min == "0" {
+		min = "odot"
+	}
+	return max + min
+}
@@ -0,0 +1,133 @@
+// Package erd finds the relationships between a selection of PostgreSQL
+// tables, including those through tables outside the selection, with their
+// cardinalities, and renders them as entity-relationship diagrams.
+//
+// The catalog metadata comes from an Introspector: a live database through
+// NewPostgresIntrospector, or a pg_dump schema file through LoadSchemaFile.
+// Analyze returns the tables and relationships, which the renderer of each
+// output format turns into a document.
+package erd
+
+import (
+	"io"
+	"log"
+	"strings"
+)
+
+// logger reports progress, and discards it unless SetLogger is called
+var logger = log.New(io.Discard, "", 0)
+
+// SetLogger sets where progress is reported, such as the number of tables
+// found and how long queries took
+func SetLogger(l *log.Logger) {
+	logger = l
+}
+
+type Table struct {
+	Name        string
+	Schema      string
+	Ghost       bool // Not selected, but on the path of a relationship between selected tables
+	Comment     string
+	Columns     []Column
+	UniqueKeys  [][]string   // Unique constraints and indexes other than the primary key
+	ForeignKeys []ForeignKey // Outgoing, including those to tables outside the selection
+}
+
+type Column struct {
+	Name       string
+	DataType   string // Without type modifiers, e.g. character varying
+	FullType   string // As declared, e.g. character varying(255)
+	IsPK       bool
+	IsFK       bool
+	IsUnique   bool // Unique on its own, other than as the primary key
+	IsNullable bool
+	Default    string // Default expression, empty if none
+	Comment    string
+}
+
+type ForeignKey struct {
+	FromSchema     string
+	FromTable      string
+	ToSchema       string
+	ToTable        string
+	Columns        []ColumnPair // In constraint order
+	ConstraintName string
+}
+
+type ColumnPair struct {
+	From string
+	To   string
+}
+
+func (fk ForeignKey) FromColumns() []string {
+	columns := make([]string, len(fk.Columns))
+	for i, pair := range fk.Columns {
+		columns[i] = pair.From
+	}
+	return columns
+}
+
+func (fk ForeignKey) ToColumns() []string {
+	columns := make([]string, len(fk.Columns))
+	for i, pair := range fk.Columns {
+		columns[i] = pair.To
+	}
+	return columns
+}
+
+type Cardinality struct {
+	Min string
+	Max string
+}
+
+type Relationship struct {
+	From            Table
+	To              Table
+	FromCardinality Cardinality
+	ToCardinality   Cardinality
+	Path            []string     // Tables in the path
+	ForeignKeys     []ForeignKey // Foreign key followed at each hop of the path
+	Label           string
+}
+
+// ColumnInfo is what the cardinality of a foreign key depends on
+type ColumnInfo struct {
+	IsNullable          bool
+	HasUniqueConstraint bool
+}
+
+// ColumnInfoKey identifies the set of referencing columns of a foreign key
+func ColumnInfoKey(fk ForeignKey) string {
+	qualifiedName := getQualifiedName(fk.FromSchema, fk.FromTable)
+	return qualifiedName + "." + strings.Join(fk.FromColumns(), ",")
+}
+
+func getQualifiedName(schema, table string) string {
+	if schema == "public" || schema == "" {
+		return table
+	}
+	// Use dot internally for unambiguous parsing
+	return schema + "." + table
+}
+
+func parseQualifiedName(qualifiedName string) (string, string) {
+	parts := strings.SplitN(qualifiedName, ".", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return "public", parts[0]
+}
+
+// markUniqueColumns flags the columns that are a unique key on their own
+func markUniqueColumns(table *Table) {
+	for _, key := range table.UniqueKeys {
+		if len(key) != 1 {
+			continue
+		}
+		for i := range table.Columns {
+			if table.Columns[i].Name == key[0] {
+				table.Columns[i].IsUnique = true
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package erd
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoTables is returned by Analyze when no table matches the options
+var ErrNoTables = errors.New("no tables matched the provided criteria")
+
+// QueryError is a failed query of the data dictionary of a database
+type QueryError struct {
+	Query string // What the query fetches, e.g. "column info"
+	Err   error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s query failed: %v", e.Query, e.Err)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// ColumnInfoError is returned by Analyze when the Introspector gives no column
+// info for a foreign key along a relationship, as its cardinality would be a
+// guess
+type ColumnInfoError struct {
+	ForeignKey ForeignKey
+}
+
+func (e *ColumnInfoError) Error() string {
+	fk := e.ForeignKey
+	return fmt.Sprintf("no column info for foreign key %s of %s (%s)",
+		fk.ConstraintName, getQualifiedName(fk.FromSchema, fk.FromTable), ColumnInfoKey(fk))
+}
@@ -0,0 +1,185 @@
+package erd
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// The HTML report is a single file with everything inlined, so that it works
+// offline: the SVG diagram, a sidebar with the columns of each table and the
+// full path of each relationship, and a script highlighting the neighbours of
+// the table clicked, matched through the data attributes of the SVG.
+
+type htmlReport struct {
+	Command       string
+	Diagram       template.HTML
+	Tables        []htmlTable
+	Relationships []htmlRelationship
+}
+
+type htmlTable struct {
+	ID      string // Qualified name, as in the SVG's data-table
+	Ghost   bool
+	Comment string
+	Columns []htmlColumn
+}
+
+type htmlColumn struct {
+	Name     string
+	DataType string
+	Keys     string
+	Nullable bool
+	Default  string
+	Comment  string
+}
+
+type htmlRelationship struct {
+	From, To  string
+	Connector string // Mermaid-style cardinality, e.g. ||--o{
+	Label     string
+	Path      []string // From, junction tables, To
+	Hops      []string // The foreign key of each hop of Path
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="generator" content="https://github.com/Dirac-Software/ersummary">
+<title>ER diagram</title>
+<style>
+  body { margin: 0; display: flex; height: 100vh; font-family: sans-serif; font-size: 14px; }
+  main { flex: 1; overflow: auto; padding: 8px; }
+  aside { width: 380px; overflow: auto; border-left: 1px solid #cccccc; padding: 0 12px; background: #fafafa; }
+  aside h2 { font-size: 16px; margin: 16px 0 8px; }
+  aside section, aside li { cursor: pointer; border-radius: 4px; padding: 4px; }
+  aside h3 { font-size: 14px; margin: 0 0 4px; font-family: monospace; }
+  aside table { border-collapse: collapse; width: 100%; font-family: monospace; font-size: 12px; }
+  aside td { padding: 1px 6px 1px 0; vertical-align: top; }
+  aside ul { list-style: none; padding: 0; margin: 0; }
+  aside code { font-size: 12px; }
+  .command { font-family: monospace; font-size: 11px; color: #666666; word-break: break-all; }
+  .path, .hops { font-size: 12px; color: #555555; }
+  .default { color: #555555; }
+  .comment { font-family: sans-serif; font-style: italic; color: #555555; white-space: pre-line; margin: 0 0 4px; }
+  .table { cursor: pointer; }
+  .table.selected rect { stroke: #d62728; stroke-width: 2; }
+  .table.neighbour rect { stroke: #1f77b4; stroke-width: 2; }
+  .relationship.highlight path, .relationship.highlight line, .relationship.highlight circle { stroke: #d62728; }
+  section.selected, li.highlight { background: #fde0e0; }
+  section.neighbour { background: #e0ecf8; }
+  .faded { opacity: 0.25; }
+</style>
+</head>
+<body>
+<main>
+{{.Diagram}}
+</main>
+<aside>
+<p class="command">{{.Command}}</p>
+<h2>Tables</h2>
+<p class="comment">Nullable columns have a ? after their type.</p>
+{{range .Tables}}<section data-table="{{.ID}}">
+<h3>{{.ID}}</h3>
+{{if .Ghost}}<p class="comment">Not selected: on the path of relationships between selected tables</p>
+{{end}}{{if .Comment}}<p class="comment">{{.Comment}}</p>
+{{end}}{{if .Columns}}<table>
+{{range .Columns}}<tr><td>{{.Name}}</td><td>{{.DataType}}{{if .Nullable}}?{{end}}</td><td>{{.Keys}}</td></tr>
+{{if .Default}}<tr><td></td><td class="default" colspan="2">default {{.Default}}</td></tr>
+{{end}}{{if .Comment}}<tr><td></td><td class="comment" colspan="2">{{.Comment}}</td></tr>
+{{end}}
+{{end}}</table>
+{{end}}</section>
+{{end}}<h2>Relationships</h2>
+<ul>
+{{range .Relationships}}<li data-from="{{.From}}" data-to="{{.To}}">
+<code>{{.From}} {{.Connector}} {{.To}}</code>{{if .Label}} {{.Label}}{{end}}
+<div class="path">{{range $i, $table := .Path}}{{if $i}} &rarr; {{end}}{{$table}}{{end}}</div>
+<div class="hops">{{range .Hops}}<div>{{.}}</div>{{end}}</div>
+</li>
+{{end}}</ul>
+</aside>
+<script>
+(function () {
+  var selected = null;
+  function select(id) {
+    selected = selected === id ? null : id;
+    var neighbours = {};
+    document.querySelectorAll("[data-from]").forEach(function (el) {
+      var related = selected !== null && (el.dataset.from === selected || el.dataset.to === selected);
+      el.classList.toggle("highlight", related);
+      el.classList.toggle("faded", selected !== null && !related);
+      if (related) {
+        neighbours[el.dataset.from] = true;
+        neighbours[el.dataset.to] = true;
+      }
+    });
+    document.querySelectorAll("[data-table]").forEach(function (el) {
+      var id = el.dataset.table;
+      el.classList.toggle("selected", id === selected);
+      el.classList.toggle("neighbour", id !== selected && neighbours[id] === true);
+      el.classList.toggle("faded", selected !== null && id !== selected && neighbours[id] !== true);
+      if (id === selected && el.tagName === "SECTION") {
+        el.scrollIntoView({ block: "nearest" });
+      }
+    });
+  }
+  document.querySelectorAll("[data-table]").forEach(function (el) {
+    el.addEventListener("click", function () { select(el.dataset.table); });
+  });
+})();
+</script>
+</body>
+</html>
+`))
+
+func generateHTMLReport(tables []Table, relationships []Relationship, options RenderOptions) (string, error) {
+	diagram, err := generateSVGDiagram(tables, relationships, options)
+	if err != nil {
+		return "", err
+	}
+	report := htmlReport{
+		Command: options.CommandLine,
+		Diagram: template.HTML(diagram),
+	}
+
+	for _, table := range tables {
+		htmlTable := htmlTable{ID: getQualifiedName(table.Schema, table.Name), Ghost: table.Ghost, Comment: table.Comment}
+		for _, col := range table.Columns {
+			htmlTable.Columns = append(htmlTable.Columns, htmlColumn{
+				Name:     col.Name,
+				DataType: getColumnType(col, options),
+				Keys:     getKeyIndicator(col),
+				Nullable: col.IsNullable,
+				Default:  col.Default,
+				Comment:  col.Comment,
+			})
+		}
+		report.Tables = append(report.Tables, htmlTable)
+	}
+
+	for _, rel := range relationships {
+		htmlRel := htmlRelationship{
+			From:      getQualifiedName(rel.From.Schema, rel.From.Name),
+			To:        getQualifiedName(rel.To.Schema, rel.To.Name),
+			Connector: getMermaidRelationType(rel.FromCardinality, rel.ToCardinality),
+			Label:     rel.Label,
+		}
+		for _, table := range rel.Path {
+			htmlRel.Path = append(htmlRel.Path, getQualifiedName(parseQualifiedName(table)))
+		}
+		for _, fk := range rel.ForeignKeys {
+			htmlRel.Hops = append(htmlRel.Hops, fmt.Sprintf("%s: %s (%s) → %s (%s)", fk.ConstraintName,
+				getQualifiedName(fk.FromSchema, fk.FromTable), strings.Join(fk.FromColumns(), ", "),
+				getQualifiedName(fk.ToSchema, fk.ToTable), strings.Join(fk.ToColumns(), ", ")))
+		}
+		report.Relationships = append(report.Relationships, htmlRel)
+	}
+
+	var sb strings.Builder
+	if err := htmlReportTemplate.Execute(&sb, report); err != nil {
+		return "", fmt.Errorf("generating HTML: %w", err)
+	}
+	return sb.String(), nil
+}
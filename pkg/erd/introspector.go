@@ -0,0 +1,94 @@
+package erd
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// Introspector supplies the catalog metadata a diagram is built from
+type Introspector interface {
+	// AllForeignKeys returns every foreign key, whatever the schema, so that
+	// paths through tables outside the selection can be found
+	AllForeignKeys(ctx context.Context) ([]ForeignKey, error)
+
+	// MatchingTables returns the tables named in tableNames (optionally
+	// schema-qualified) or whose name matches tableRegex, within schemas
+	MatchingTables(ctx context.Context, schemas []string, tableNames []string, tableRegex string) ([]Table, error)
+
+	// ColumnInfo returns the nullability and uniqueness of the referencing
+	// columns of each foreign key, keyed by ColumnInfoKey
+	ColumnInfo(ctx context.Context, foreignKeys []ForeignKey) (map[string]ColumnInfo, error)
+
+	// TableColumns returns the tables with their columns, flagging the ones
+	// that take part in the given foreign keys
+	TableColumns(ctx context.Context, tables []Table, foreignKeys []ForeignKey) ([]Table, error)
+}
+
+// PostgresIntrospector queries the data dictionary of a live database
+type PostgresIntrospector struct {
+	db *sql.DB
+}
+
+func NewPostgresIntrospector(db *sql.DB) *PostgresIntrospector {
+	return &PostgresIntrospector{db: db}
+}
+
+// The errors of PostgresIntrospector are QueryErrors, naming the query
+
+func (p *PostgresIntrospector) AllForeignKeys(ctx context.Context) ([]ForeignKey, error) {
+	foreignKeys, err := getAllForeignKeys(ctx, p.db)
+	if err != nil {
+		return nil, &QueryError{Query: "foreign keys", Err: err}
+	}
+	return foreignKeys, nil
+}
+
+func (p *PostgresIntrospector) MatchingTables(ctx context.Context, schemas []string, tableNames []string, tableRegex string) ([]Table, error) {
+	tables, err := getMatchingTables(ctx, p.db, schemas, tableNames, tableRegex)
+	if err != nil {
+		return nil, &QueryError{Query: "matching tables", Err: err}
+	}
+	return tables, nil
+}
+
+func (p *PostgresIntrospector) ColumnInfo(ctx context.Context, foreignKeys []ForeignKey) (map[string]ColumnInfo, error) {
+	columnInfo, err := getColumnInfo(ctx, p.db, foreignKeys)
+	if err != nil {
+		return nil, &QueryError{Query: "column info", Err: err}
+	}
+	return columnInfo, nil
+}
+
+func (p *PostgresIntrospector) TableColumns(ctx context.Context, tables []Table, foreignKeys []ForeignKey) ([]Table, error) {
+	tables, err := getTableColumns(ctx, p.db, tables, foreignKeys)
+	if err != nil {
+		return nil, &QueryError{Query: "table columns", Err: err}
+	}
+	return tables, nil
+}
+
+// CacheForeignKeys returns an Introspector fetching the foreign keys of the
+// database only once, for analyzing several diagrams of the same database
+func CacheForeignKeys(introspector Introspector) Introspector {
+	return &foreignKeyCache{Introspector: introspector}
+}
+
+type foreignKeyCache struct {
+	Introspector
+	mu          sync.Mutex
+	foreignKeys []ForeignKey // nil until fetched successfully
+}
+
+func (c *foreignKeyCache) AllForeignKeys(ctx context.Context) ([]ForeignKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.foreignKeys == nil {
+		foreignKeys, err := c.Introspector.AllForeignKeys(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.foreignKeys = append([]ForeignKey{}, foreignKeys...)
+	}
+	return c.foreignKeys, nil
+}
@@ -0,0 +1,129 @@
+package erd
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// JSONSchemaVersion is increased whenever the JSON output changes in a way
+// that could break its consumers
+const JSONSchemaVersion = 1
+
+// JSONDocument is the output of -format json
+type JSONDocument struct {
+	Version       int                `json:"version"` // JSONSchemaVersion
+	Command       string             `json:"command"`
+	Tables        []JSONTable        `json:"tables"`
+	Relationships []JSONRelationship `json:"relationships"`
+}
+
+type JSONTable struct {
+	Schema  string       `json:"schema"`
+	Name    string       `json:"name"`
+	Ghost   bool         `json:"ghost"` // Not selected, but on the path of a relationship
+	Comment string       `json:"comment"`
+	Columns []JSONColumn `json:"columns"`
+}
+
+type JSONColumn struct {
+	Name       string `json:"name"`
+	DataType   string `json:"data_type"` // Without type modifiers
+	FullType   string `json:"full_type"` // As declared
+	IsPK       bool   `json:"is_pk"`
+	IsFK       bool   `json:"is_fk"`
+	IsUnique   bool   `json:"is_unique"` // Unique on its own, other than as the primary key
+	IsNullable bool   `json:"is_nullable"`
+	Default    string `json:"default"`
+	Comment    string `json:"comment"`
+}
+
+type JSONTableRef struct {
+	Schema string `json:"schema"`
+	Name   string `json:"name"`
+}
+
+// JSONRelationship is a relationship between two selected tables, either
+// direct or through the junction tables listed in Path
+type JSONRelationship struct {
+	From            JSONTableRef     `json:"from"`
+	To              JSONTableRef     `json:"to"`
+	FromCardinality JSONCardinality  `json:"from_cardinality"`
+	ToCardinality   JSONCardinality  `json:"to_cardinality"`
+	Path            []JSONTableRef   `json:"path"`         // From, junction tables, To
+	ForeignKeys     []JSONForeignKey `json:"foreign_keys"` // One per hop of Path
+	Label           string           `json:"label"`
+}
+
+// JSONCardinality is the number of rows at one end of a relationship: Min is
+// "0" or "1", Max is "1" or "*"
+type JSONCardinality struct {
+	Min string `json:"min"`
+	Max string `json:"max"`
+}
+
+type JSONForeignKey struct {
+	ConstraintName string       `json:"constraint_name"`
+	From           JSONTableRef `json:"from"`
+	FromColumns    []string     `json:"from_columns"`
+	To             JSONTableRef `json:"to"`
+	ToColumns      []string     `json:"to_columns"`
+}
+
+func generateJSONDocument(tables []Table, relationships []Relationship, options RenderOptions) (string, error) {
+	doc := JSONDocument{
+		Version:       JSONSchemaVersion,
+		Command:       options.CommandLine,
+		Tables:        make([]JSONTable, 0, len(tables)),
+		Relationships: make([]JSONRelationship, 0, len(relationships)),
+	}
+
+	for _, table := range tables {
+		jsonTable := JSONTable{Schema: table.Schema, Name: table.Name, Ghost: table.Ghost, Comment: table.Comment, Columns: make([]JSONColumn, 0, len(table.Columns))}
+		for _, col := range table.Columns {
+			jsonTable.Columns = append(jsonTable.Columns, JSONColumn{
+				Name:       col.Name,
+				DataType:   col.DataType,
+				FullType:   col.FullType,
+				IsPK:       col.IsPK,
+				IsFK:       col.IsFK,
+				IsUnique:   col.IsUnique,
+				IsNullable: col.IsNullable,
+				Default:    col.Default,
+				Comment:    col.Comment,
+			})
+		}
+		doc.Tables = append(doc.Tables, jsonTable)
+	}
+
+	for _, rel := range relationships {
+		jsonRel := JSONRelationship{
+			From:            JSONTableRef{Schema: rel.From.Schema, Name: rel.From.Name},
+			To:              JSONTableRef{Schema: rel.To.Schema, Name: rel.To.Name},
+			FromCardinality: JSONCardinality{Min: rel.FromCardinality.Min, Max: rel.FromCardinality.Max},
+			ToCardinality:   JSONCardinality{Min: rel.ToCardinality.Min, Max: rel.ToCardinality.Max},
+			Path:            make([]JSONTableRef, 0, len(rel.Path)),
+			ForeignKeys:     make([]JSONForeignKey, 0, len(rel.ForeignKeys)),
+			Label:           rel.Label,
+		}
+		for _, table := range rel.Path {
+			schema, name := parseQualifiedName(table)
+			jsonRel.Path = append(jsonRel.Path, JSONTableRef{Schema: schema, Name: name})
+		}
+		for _, fk := range rel.ForeignKeys {
+			jsonRel.ForeignKeys = append(jsonRel.ForeignKeys, JSONForeignKey{
+				ConstraintName: fk.ConstraintName,
+				From:           JSONTableRef{Schema: fk.FromSchema, Name: fk.FromTable},
+				FromColumns:    fk.FromColumns(),
+				To:             JSONTableRef{Schema: fk.ToSchema, Name: fk.ToTable},
+				ToColumns:      fk.ToColumns(),
+			})
+		}
+		doc.Relationships = append(doc.Relationships, jsonRel)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encoding JSON: %w", err)
+	}
+	return string(data), nil
+}
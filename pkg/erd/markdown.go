@@ -0,0 +1,199 @@
+package erd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The Markdown data dictionary starts with the Mermaid diagram, then has a
+// section per table with its columns and constraints. Related tables link to
+// each other's sections through explicit anchors, which don't depend on how a
+// Markdown renderer derives them from headings.
+
+func generateMarkdownDictionary(tables []Table, relationships []Relationship, options RenderOptions) (string, error) {
+	var sb strings.Builder
+
+	sb.WriteString("# Data dictionary\n\n")
+	sb.WriteString("<!-- Generated by https://github.com/Dirac-Software/ersummary -->\n")
+	sb.WriteString(fmt.Sprintf("<!-- Command: %s -->\n\n", strings.ReplaceAll(options.CommandLine, "--", "- -")))
+
+	// The diagram shows only the relationships, the columns are below
+	diagramTables := make([]Table, len(tables))
+	for i, table := range tables {
+		diagramTables[i] = Table{Name: table.Name, Schema: table.Schema, Ghost: table.Ghost}
+	}
+	diagram, err := generateMermaidDiagram(diagramTables, relationships, options)
+	if err != nil {
+		return "", err
+	}
+	sb.WriteString("```mermaid\n")
+	sb.WriteString(diagram)
+	sb.WriteString("```\n\n")
+
+	selected := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		sb.WriteString(fmt.Sprintf("* %s\n", getMarkdownTableLink(getQualifiedName(table.Schema, table.Name))))
+		selected[getQualifiedName(table.Schema, table.Name)] = true
+	}
+	sb.WriteString("\n")
+
+	for _, table := range tables {
+		qualifiedName := getQualifiedName(table.Schema, table.Name)
+		sb.WriteString(fmt.Sprintf("<a id=\"%s\"></a>\n\n## %s\n\n", getMarkdownAnchor(qualifiedName), getMarkdownText(qualifiedName)))
+		if table.Ghost {
+			sb.WriteString("*Not selected: on the path of relationships between selected tables.*\n\n")
+		}
+		if table.Comment != "" {
+			sb.WriteString(getMarkdownText(table.Comment) + "\n\n")
+		}
+
+		if len(table.Columns) > 0 {
+			sb.WriteString("| Column | Type | Nullable | Default | Key | Comment |\n")
+			sb.WriteString("|--------|------|----------|---------|-----|---------|\n")
+			for _, col := range table.Columns {
+				nullable := "no"
+				if col.IsNullable {
+					nullable = "yes"
+				}
+				// The exact type, whatever the diagram shows
+				dataType := col.FullType
+				if dataType == "" {
+					dataType = col.DataType
+				}
+				sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s |\n",
+					getMarkdownCodeCell(col.Name),
+					getMarkdownCell(dataType),
+					nullable,
+					getMarkdownCodeCell(col.Default),
+					getKeyIndicator(col),
+					getMarkdownCell(col.Comment)))
+			}
+			sb.WriteString("\n")
+		}
+
+		var pkColumns []string
+		for _, col := range table.Columns {
+			if col.IsPK {
+				pkColumns = append(pkColumns, col.Name)
+			}
+		}
+		if len(pkColumns) > 0 {
+			sb.WriteString(fmt.Sprintf("**Primary key:** %s\n\n", getMarkdownColumns(pkColumns)))
+		}
+
+		if len(table.UniqueKeys) > 0 {
+			sb.WriteString("**Unique:**\n\n")
+			for _, key := range table.UniqueKeys {
+				sb.WriteString(fmt.Sprintf("* %s\n", getMarkdownColumns(key)))
+			}
+			sb.WriteString("\n")
+		}
+
+		if len(table.ForeignKeys) > 0 {
+			sb.WriteString("**Foreign keys:**\n\n")
+			for _, fk := range table.ForeignKeys {
+				toName := getQualifiedName(fk.ToSchema, fk.ToTable)
+				target := getMarkdownCode(toName)
+				if selected[toName] {
+					target = getMarkdownTableLink(toName)
+				}
+				sb.WriteString(fmt.Sprintf("* %s %s references %s %s\n",
+					getMarkdownCode(fk.ConstraintName), getMarkdownColumns(fk.FromColumns()), target, getMarkdownColumns(fk.ToColumns())))
+			}
+			sb.WriteString("\n")
+		}
+
+		// Foreign keys of the other selected tables to this one
+		var referencedBy []string
+		for _, other := range tables {
+			for _, fk := range other.ForeignKeys {
+				if getQualifiedName(fk.ToSchema, fk.ToTable) == qualifiedName {
+					referencedBy = append(referencedBy, fmt.Sprintf("* %s %s by %s\n",
+						getMarkdownColumns(fk.ToColumns()), getMarkdownTableLink(getQualifiedName(other.Schema, other.Name)),
+						getMarkdownColumns(fk.FromColumns())))
+				}
+			}
+		}
+		if len(referencedBy) > 0 {
+			sb.WriteString("**Referenced by:**\n\n")
+			sb.WriteString(strings.Join(referencedBy, ""))
+			sb.WriteString("\n")
+		}
+
+		// Relationships through junction tables
+		var indirect []string
+		for _, rel := range relationships {
+			if len(rel.Path) <= 2 {
+				continue
+			}
+			fromName := getQualifiedName(rel.From.Schema, rel.From.Name)
+			toName := getQualifiedName(rel.To.Schema, rel.To.Name)
+			relType := getMermaidRelationType(rel.FromCardinality, rel.ToCardinality)
+			switch qualifiedName {
+			case fromName:
+				indirect = append(indirect, fmt.Sprintf("* %s %s: %s\n", getMarkdownCode(relType), getMarkdownTableLink(toName), getMarkdownText(rel.Label)))
+			case toName:
+				relType = getMermaidRelationType(rel.ToCardinality, rel.FromCardinality)
+				indirect = append(indirect, fmt.Sprintf("* %s %s: %s\n", getMarkdownCode(relType), getMarkdownTableLink(fromName), getMarkdownText(rel.Label)))
+			}
+		}
+		if len(indirect) > 0 {
+			sb.WriteString("**Related through other tables:**\n\n")
+			sb.WriteString(strings.Join(indirect, ""))
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String(), nil
+}
+
+var markdownAnchorUnsafe = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// getMarkdownAnchor returns the id of a table's section
+func getMarkdownAnchor(qualifiedName string) string {
+	return "table-" + markdownAnchorUnsafe.ReplaceAllString(strings.ToLower(qualifiedName), "-")
+}
+
+func getMarkdownTableLink(qualifiedName string) string {
+	return fmt.Sprintf("[%s](#%s)", getMarkdownText(qualifiedName), getMarkdownAnchor(qualifiedName))
+}
+
+func getMarkdownColumns(columns []string) string {
+	return "(" + getMarkdownCode(strings.Join(columns, ", ")) + ")"
+}
+
+// getMarkdownCode formats text as an inline code span
+func getMarkdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	s = strings.ReplaceAll(s, "\n", " ")
+	// A code span is delimited by a longer run of backticks than it contains
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return fence + s + fence
+}
+
+// getMarkdownCodeCell formats text as an inline code span in a table cell,
+// where pipes must be escaped even in code
+func getMarkdownCodeCell(s string) string {
+	return strings.ReplaceAll(getMarkdownCode(s), "|", "\\|")
+}
+
+var markdownSpecial = regexp.MustCompile("([\\\\`*_{}\\[\\]<>#|])")
+
+// getMarkdownText escapes text so that it is displayed as is
+func getMarkdownText(s string) string {
+	return markdownSpecial.ReplaceAllString(s, "\\$1")
+}
+
+// getMarkdownCell escapes text for a table cell, which must fit on one line
+func getMarkdownCell(s string) string {
+	return strings.ReplaceAll(getMarkdownText(s), "\n", "<br>")
+}
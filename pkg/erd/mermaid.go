@@ -0,0 +1,178 @@
+package erd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+func getQualifiedTableName(table Table) string {
+	// Get qualified name with dots, then convert to underscores for Mermaid
+	// Mermaid only allows alphanumeric and underscore in entity names
+	qualifiedName := getQualifiedName(table.Schema, table.Name)
+	return strings.ReplaceAll(qualifiedName, ".", "_")
+}
+
+func generateMermaidDiagram(tables []Table, relationships []Relationship, options RenderOptions) (string, error) {
+	var sb strings.Builder
+
+	// Add comments at the top
+	sb.WriteString("%%{init: {'theme':'neutral'}}%%\n")
+	sb.WriteString("%% Generated by https://github.com/Dirac-Software/ersummary\n")
+	sb.WriteString(fmt.Sprintf("%%%% Command: %s\n", options.CommandLine))
+	sb.WriteString("\nerDiagram\n")
+
+	ghosts := getGhostTables(tables)
+	for _, table := range tables {
+		qualifiedName := getQualifiedTableName(table)
+		// Entities can't have comments, so the table's is a Mermaid comment
+		if table.Comment != "" {
+			sb.WriteString(fmt.Sprintf("    %%%% %s\n", strings.Join(strings.Fields(table.Comment), " ")))
+		}
+		sb.WriteString(fmt.Sprintf("    %s {\n", qualifiedName))
+		// Ghost tables are drawn without their columns
+		if len(table.Columns) > 0 && !table.Ghost {
+			for _, col := range table.Columns {
+				dataType := dataTypeToMermaid(col.DataType)
+				if options.ExactTypes {
+					dataType = getMermaidExactType(getColumnType(col, options))
+				}
+				attribute := []string{dataType, col.Name}
+				if keys := getKeyIndicator(col); keys != "" {
+					attribute = append(attribute, keys)
+				}
+				if comment := getMermaidComment(col); comment != "" {
+					attribute = append(attribute, comment)
+				}
+				sb.WriteString(fmt.Sprintf("        %s\n", strings.Join(attribute, " ")))
+			}
+		}
+		sb.WriteString("    }\n")
+	}
+
+	for _, rel := range relationships {
+		relType := getMermaidRelationType(rel.FromCardinality, rel.ToCardinality)
+		// Entities can't be styled, so relationships with ghost tables are
+		// dashed (non-identifying) instead
+		if isGhostRelationship(rel, ghosts) {
+			relType = strings.Replace(relType, "--", "..", 1)
+		}
+		fromName := getQualifiedTableName(rel.From)
+		toName := getQualifiedTableName(rel.To)
+		sb.WriteString(fmt.Sprintf("    %s %s %s : \"%s\"\n",
+			fromName,
+			relType,
+			toName,
+			rel.Label))
+	}
+
+	return sb.String(), nil
+}
+
+// getMermaidComment describes a column in an attribute comment: whether it is
+// NOT NULL (implied for primary keys), its default and its own comment. The
+// comment can't contain double quotes or span lines.
+func getMermaidComment(col Column) string {
+	var parts []string
+	if !col.IsNullable && !col.IsPK {
+		parts = append(parts, "not null")
+	}
+	if col.Default != "" {
+		parts = append(parts, "default "+col.Default)
+	}
+	if col.Comment != "" {
+		parts = append(parts, col.Comment)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "\"" + strings.ReplaceAll(strings.Join(strings.Fields(strings.Join(parts, "; ")), " "), "\"", "'") + "\""
+}
+
+func dataTypeToMermaid(pgType string) string {
+	switch {
+	case strings.Contains(pgType, "int"):
+		return "int"
+	case strings.Contains(pgType, "char"), strings.Contains(pgType, "text"):
+		return "string"
+	case strings.Contains(pgType, "timestamp"), strings.Contains(pgType, "date"), strings.Contains(pgType, "time"):
+		return "datetime"
+	case strings.Contains(pgType, "bool"):
+		return "boolean"
+	case strings.Contains(pgType, "numeric"), strings.Contains(pgType, "decimal"), strings.Contains(pgType, "real"), strings.Contains(pgType, "double"):
+		return "float"
+	default:
+		return getMermaidExactType(pgType)
+	}
+}
+
+// mermaidTypeAliases shortens the multi-word type names format_type returns
+var mermaidTypeAliases = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`\bcharacter varying\b`), "varchar"},
+	{regexp.MustCompile(`\bcharacter\b`), "char"},
+	{regexp.MustCompile(`\bbit varying\b`), "varbit"},
+	{regexp.MustCompile(`\bdouble precision\b`), "float8"},
+	{regexp.MustCompile(`\b(timestamp|time)(\(\d+\))? with time zone\b`), "${1}tz$2"},
+	{regexp.MustCompile(`\b(timestamp|time)(\(\d+\))? without time zone\b`), "$1$2"},
+}
+
+var mermaidTypeUnsafe = regexp.MustCompile(`[^A-Za-z0-9_()\[\]-]`)
+
+// getMermaidExactType keeps a PostgreSQL type as it is, as far as Mermaid
+// allows: type names must start with a letter and only contain letters,
+// digits, hyphens, underscores, parentheses and brackets, so multi-word
+// types are shortened to their aliases and other characters (such as the
+// comma of numeric(10,2) or the dot of a schema-qualified domain) become
+// underscores
+func getMermaidExactType(pgType string) string {
+	for _, alias := range mermaidTypeAliases {
+		pgType = alias.pattern.ReplaceAllString(pgType, alias.replacement)
+	}
+	pgType = mermaidTypeUnsafe.ReplaceAllString(pgType, "_")
+	if pgType == "" || !(pgType[0] >= 'A' && pgType[0] <= 'Z' || pgType[0] >= 'a' && pgType[0] <= 'z') {
+		pgType = "t" + pgType
+	}
+	return pgType
+}
+
+func getMermaidRelationType(fromCard, toCard Cardinality) string {
+	return getCardinalitySymbol(fromCard) + "--" + reverseString(getCardinalitySymbol(toCard))
+}
+
+func getCardinalitySymbol(card Cardinality) string {
+	minMax := card.Min + card.Max
+	switch minMax {
+	case "01":
+		return "|o"
+	case "11":
+		return "||"
+	case "0*":
+		return "}o"
+	case "1*":
+		return "}|"
+	default:
+		logger.Printf("Unexpected cardinality: min=%s, max=%s", card.Min, card.Max)
+		return "||"
+	}
+}
+
+func reverseString(s string) string {
+	runes := []rune(s)
+	// First reverse the string
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	// Then flip the curly braces
+	for i := range runes {
+		switch runes[i] {
+		case '{':
+			runes[i] = '}'
+		case '}':
+			runes[i] = '{'
+		}
+	}
+	return string(runes)
+}
@@ -0,0 +1,61 @@
+package erd
+
+import (
+	"sort"
+	"strings"
+)
+
+// Orders of the tables of a Result, for Options.Sort. Columns are always in
+// the order of the table definition, and relationships in the order of the
+// tables they relate.
+const (
+	SortAlpha  = "alpha"  // By name, then schema
+	SortSchema = "schema" // By schema, then name
+	SortInput  = "input"  // As named in Options.Tables, then the others by name
+)
+
+// sortedTables returns the tables of a map keyed by schema.table, by schema
+// then name, so that introspectors don't return them in map order
+func sortedTables(tableMap map[string]Table) []Table {
+	tables := make([]Table, 0, len(tableMap))
+	for _, t := range tableMap {
+		tables = append(tables, t)
+	}
+	sortTables(tables, SortSchema, nil)
+	return tables
+}
+
+// sortTables sorts tables in one of the Sort orders, tableNames being the
+// names given for SortInput
+func sortTables(tables []Table, order string, tableNames []string) {
+	sort.SliceStable(tables, func(i, j int) bool {
+		a, b := tables[i], tables[j]
+		if order == SortInput {
+			if rankA, rankB := getTableRank(a, tableNames), getTableRank(b, tableNames); rankA != rankB {
+				return rankA < rankB
+			}
+		}
+		if order == SortSchema && a.Schema != b.Schema {
+			return a.Schema < b.Schema
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Schema < b.Schema
+	})
+}
+
+// getTableRank returns the position of the first of tableNames naming the
+// table, with or without its schema, or len(tableNames) if none does
+func getTableRank(table Table, tableNames []string) int {
+	for i, name := range tableNames {
+		if strings.Contains(name, ".") {
+			if name == table.Schema+"."+table.Name {
+				return i
+			}
+		} else if name == table.Name {
+			return i
+		}
+	}
+	return len(tableNames)
+}
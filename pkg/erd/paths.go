@@ -0,0 +1,559 @@
+package erd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/simple"
+)
+
+type tableNode struct {
+	id   int64
+	name string
+}
+
+func (n tableNode) ID() int64 {
+	return n.id
+}
+
+// buildForeignKeyGraph returns the graph of the tables taking part in foreign
+// keys, with an edge from each referenced table to the referencing one
+func buildForeignKeyGraph(allForeignKeys []ForeignKey) (*simple.DirectedGraph, map[string]graph.Node, map[int64]string) {
+	// Build directed graph using gonum
+	g := simple.NewDirectedGraph()
+	tableToNode := make(map[string]graph.Node)
+	nodeToTable := make(map[int64]string)
+	nodeID := int64(0)
+
+	// Add all tables as nodes
+	for _, fk := range allForeignKeys {
+		fromQualified := getQualifiedName(fk.FromSchema, fk.FromTable)
+		toQualified := getQualifiedName(fk.ToSchema, fk.ToTable)
+
+		if _, exists := tableToNode[fromQualified]; !exists {
+			node := tableNode{id: nodeID, name: fromQualified}
+			g.AddNode(node)
+			tableToNode[fromQualified] = node
+			nodeToTable[nodeID] = fromQualified
+			nodeID++
+		}
+		if _, exists := tableToNode[toQualified]; !exists {
+			node := tableNode{id: nodeID, name: toQualified}
+			g.AddNode(node)
+			tableToNode[toQualified] = node
+			nodeToTable[nodeID] = toQualified
+			nodeID++
+		}
+	}
+
+	// Add edges for foreign keys (inverted direction)
+	// FK goes from child to parent, but we want edges from parent to child
+	// to find common descendants
+	for _, fk := range allForeignKeys {
+		fromQualified := getQualifiedName(fk.FromSchema, fk.FromTable)
+		toQualified := getQualifiedName(fk.ToSchema, fk.ToTable)
+		if fromQualified == toQualified {
+			continue // Self-references can't be graph edges, they are handled separately
+		}
+		fromNode := tableToNode[fromQualified]
+		toNode := tableToNode[toQualified]
+		// Invert the edge direction: parent -> child
+		g.SetEdge(g.NewEdge(toNode, fromNode))
+	}
+
+	return g, tableToNode, nodeToTable
+}
+
+// calculateCardinalities finds the relationships between the selected tables.
+// Each pair of tables gets its shortest route, then, unless maxPaths is 1, up
+// to maxPaths routes in all (-1 for all of them), the others being at most
+// maxHops foreign keys long.
+func calculateCardinalities(schemas []string, selectedTables []string, allForeignKeys []ForeignKey, columnInfo map[string]ColumnInfo, maxPaths, maxHops int) []Relationship {
+	if len(allForeignKeys) == 0 {
+		return []Relationship{}
+	}
+
+	// Use first schema for backward compatibility in relationship generation
+	schema := schemas[0]
+
+	g, tableToNode, nodeToTable := buildForeignKeyGraph(allForeignKeys)
+
+	// Create map of selected tables for quick lookup
+	selectedMap := make(map[string]bool)
+	for _, t := range selectedTables {
+		selectedMap[t] = true
+	}
+
+	// Shortest paths are only needed from the selected tables, so search from
+	// each of them rather than between all pairs of tables of the database
+	searches := make(map[string]*shortestPaths)
+	for _, table := range selectedTables {
+		if node, ok := tableToNode[table]; ok {
+			searches[table] = searchShortestPaths(g, node, nodeToTable)
+		}
+	}
+
+	// Create FK lookup map using qualified names
+	// Tables may be linked by several constraints, so keep all of them
+	fkMap := make(map[string][]ForeignKey)
+	for _, fk := range allForeignKeys {
+		fromQualified := getQualifiedName(fk.FromSchema, fk.FromTable)
+		toQualified := getQualifiedName(fk.ToSchema, fk.ToTable)
+		key := fromQualified + "->" + toQualified
+		fkMap[key] = append(fkMap[key], fk)
+	}
+
+	var relationships []Relationship
+
+	// Self-referencing foreign keys (hierarchies) of each selected table
+	for _, table := range selectedTables {
+		for _, fk := range fkMap[table+"->"+table] {
+			relationships = append(relationships, calculateSelfCardinality(table, fk, columnInfo))
+		}
+	}
+
+	// Find relationships between all pairs of selected tables
+	for i, tableA := range selectedTables {
+		for j, tableB := range selectedTables {
+			if i >= j { // Skip self and already processed pairs
+				continue
+			}
+
+			searchA, okA := searches[tableA]
+			searchB, okB := searches[tableB]
+			if !okA || !okB {
+				continue
+			}
+
+			shortest := findShortestRelationships(tableA, tableB, searchA, searchB, tableToNode, nodeToTable, selectedMap, fkMap, columnInfo, schema)
+			relationships = append(relationships, shortest...)
+			if maxPaths == 1 {
+				continue
+			}
+
+			// Then the other routes, the shortest being among them unless
+			// it is longer than maxHops. When the shortest went through
+			// another selected table, only these are drawn.
+			found := 0
+			if len(shortest) > 0 {
+				found = 1
+			}
+			for _, route := range findRoutes(tableA, tableB, g, tableToNode, nodeToTable, selectedMap, maxHops) {
+				if maxPaths > 0 && found >= maxPaths {
+					break
+				}
+				if len(shortest) > 0 && isSameRoute(route, shortest[0].Path) {
+					continue
+				}
+				relationships = append(relationships, getRouteRelationships(route, fkMap, columnInfo, schema)...)
+				found++
+			}
+		}
+	}
+
+	return relationships
+}
+
+// findShortestRelationships returns the relationships along the shortest route
+// between two tables: a chain of foreign keys from one to the other, or through
+// their closest common descendant, preferring the chain when they tie. Paths
+// with other selected tables in the middle are left out.
+func findShortestRelationships(tableA, tableB string, searchA, searchB *shortestPaths, tableToNode map[string]graph.Node, nodeToTable map[int64]string, selectedMap map[string]bool, fkMap map[string][]ForeignKey, columnInfo map[string]ColumnInfo, schema string) []Relationship {
+	// Direct path between A and B (considering inverted graph)
+	direct := findDirectPath(searchA.source, searchB.source, searchA, searchB, nodeToTable, selectedMap, fkMap, columnInfo, schema)
+
+	// Find common descendant (highest table with FKs to both A and B)
+	var indirect []Relationship
+	lca, lcaNode := findLCAUsingGonum(searchA, searchB, tableToNode, nodeToTable, selectedMap)
+	if lca != "" {
+		// Get paths from A to C and B to C (inverted graph)
+		pathAtoC := searchA.pathTo(lcaNode)
+		pathBtoC := searchB.pathTo(lcaNode)
+
+		if len(pathAtoC) > 0 && len(pathBtoC) > 0 {
+			// Convert to string paths and reverse them to get C->A and C->B
+			strPathAtoC := nodesToTables(pathAtoC, nodeToTable)
+			strPathBtoC := nodesToTables(pathBtoC, nodeToTable)
+
+			// Reverse paths to get the actual FK direction
+			strPathCtoA := make([]string, len(strPathAtoC))
+			strPathCtoB := make([]string, len(strPathBtoC))
+			for i := range strPathAtoC {
+				strPathCtoA[i] = strPathAtoC[len(strPathAtoC)-1-i]
+			}
+			for i := range strPathBtoC {
+				strPathCtoB[i] = strPathBtoC[len(strPathBtoC)-1-i]
+			}
+
+			// Calculate combined cardinality
+			indirect = calculateLCACardinality(lca, tableA, tableB, strPathCtoA, strPathCtoB, fkMap, columnInfo, schema)
+		}
+	}
+
+	// Prefer the shorter route, and the direct one when they tie
+	switch {
+	case len(direct) > 0 && (len(indirect) == 0 || len(direct[0].Path) <= len(indirect[0].Path)):
+		return direct
+	case len(indirect) > 0:
+		return indirect
+	}
+	return nil
+}
+
+func nodesToTables(nodes []graph.Node, nodeToTable map[int64]string) []string {
+	tables := make([]string, len(nodes))
+	for i, node := range nodes {
+		tables[i] = nodeToTable[node.ID()]
+	}
+	return tables
+}
+
+// shortestPaths are the shortest paths from one table down the graph, to the
+// tables referencing it directly or not
+type shortestPaths struct {
+	source graph.Node
+	dist   map[int64]int
+	parent map[int64]graph.Node
+}
+
+// searchShortestPaths runs a breadth-first search from a table. Neighbours are
+// visited in name order, so that of several shortest paths to a table, the
+// first by the names of its tables is always the one found.
+func searchShortestPaths(g graph.Directed, source graph.Node, nodeToTable map[int64]string) *shortestPaths {
+	search := &shortestPaths{
+		source: source,
+		dist:   map[int64]int{source.ID(): 0},
+		parent: make(map[int64]graph.Node),
+	}
+
+	queue := []graph.Node{source}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		neighbours := graph.NodesOf(g.From(node.ID()))
+		sort.Slice(neighbours, func(i, j int) bool {
+			return nodeToTable[neighbours[i].ID()] < nodeToTable[neighbours[j].ID()]
+		})
+		for _, neighbour := range neighbours {
+			if _, seen := search.dist[neighbour.ID()]; seen {
+				continue
+			}
+			search.dist[neighbour.ID()] = search.dist[node.ID()] + 1
+			search.parent[neighbour.ID()] = node
+			queue = append(queue, neighbour)
+		}
+	}
+
+	return search
+}
+
+// pathTo returns the shortest path from the source to a node, or nil if it
+// can't be reached
+func (s *shortestPaths) pathTo(node graph.Node) []graph.Node {
+	if _, ok := s.dist[node.ID()]; !ok {
+		return nil
+	}
+	path := []graph.Node{node}
+	for node.ID() != s.source.ID() {
+		node = s.parent[node.ID()]
+		path = append(path, node)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+func isValidPath(path []string, selectedMap map[string]bool) bool {
+	// Check intermediate nodes (not start or end)
+	for i := 1; i < len(path)-1; i++ {
+		if selectedMap[path[i]] {
+			return false
+		}
+	}
+	return true
+}
+
+func findDirectPath(nodeA, nodeB graph.Node, searchA, searchB *shortestPaths, nodeToTable map[int64]string, selectedMap map[string]bool, fkMap map[string][]ForeignKey, columnInfo map[string]ColumnInfo, schema string) []Relationship {
+	// Try path from B to A (means A has FK to B in inverted graph)
+	if rels := tryDirectPath(searchB, nodeA, nodeToTable, selectedMap, fkMap, columnInfo, schema); len(rels) > 0 {
+		return rels
+	}
+
+	// Try path from A to B (means B has FK to A in inverted graph)
+	return tryDirectPath(searchA, nodeB, nodeToTable, selectedMap, fkMap, columnInfo, schema)
+}
+
+func tryDirectPath(search *shortestPaths, toNode graph.Node, nodeToTable map[int64]string, selectedMap map[string]bool, fkMap map[string][]ForeignKey, columnInfo map[string]ColumnInfo, schema string) []Relationship {
+	path := search.pathTo(toNode)
+	if len(path) == 0 {
+		return nil
+	}
+
+	strPath := nodesToTables(path, nodeToTable)
+	if !isValidPath(strPath, selectedMap) {
+		return nil
+	}
+
+	// Reverse to get actual FK direction (graph is inverted)
+	reversedPath := make([]string, len(strPath))
+	for i := range strPath {
+		reversedPath[i] = strPath[len(strPath)-1-i]
+	}
+
+	return getRouteRelationships(reversedPath, fkMap, columnInfo, schema)
+}
+
+// getRouteRelationships returns a relationship per combination of constraints
+// along a route
+func getRouteRelationships(route []string, fkMap map[string][]ForeignKey, columnInfo map[string]ColumnInfo, schema string) []Relationship {
+	choices := getForeignKeyChoices(route, fkMap)
+	var relationships []Relationship
+	for _, fks := range choices {
+		relationship := calculatePathCardinality(route, fks, columnInfo, schema)
+		if relationship != nil {
+			relationship.Path = route
+			relationship.ForeignKeys = fks
+			relationship.Label = getRelationshipLabel(route, fks, len(choices) > 1)
+			relationships = append(relationships, *relationship)
+		}
+	}
+	return relationships
+}
+
+// routeStep is the tables one foreign key away, up to those referenced or down
+// to those referencing
+type routeStep struct {
+	tables []string
+	up     bool
+}
+
+// findRoutes lists the routes of at most maxHops foreign keys between two
+// selected tables that don't go through other selected tables, shortest
+// first. Like the shortest routes, they go from tableA down to the tables
+// referencing it, then up through the tables referenced to tableB; either
+// part may be empty. Routes that only go down are reversed, so that they
+// start from the referencing table as direct paths do.
+func findRoutes(tableA, tableB string, g graph.Directed, tableToNode map[string]graph.Node, nodeToTable map[int64]string, selectedMap map[string]bool, maxHops int) [][]string {
+	var routes [][]string
+	seen := make(map[string]bool)
+	visited := map[string]bool{tableA: true}
+	route := []string{tableA}
+
+	// Edges go from the referenced table to the referencing one
+	neighbours := func(nodes graph.Nodes) []string {
+		var names []string
+		for nodes.Next() {
+			names = append(names, nodeToTable[nodes.Node().ID()])
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	var walk func(table string, up bool)
+	walk = func(table string, up bool) {
+		if len(route) > maxHops {
+			return
+		}
+		node := tableToNode[table]
+		// Up first, so that tables referencing each other give the route
+		// from tableA, as findDirectPath does
+		steps := []routeStep{{neighbours(g.To(node.ID())), true}}
+		if !up {
+			steps = append(steps, routeStep{neighbours(g.From(node.ID())), false})
+		}
+
+		for _, step := range steps {
+			for _, next := range step.tables {
+				if next == tableB {
+					found := append(append([]string(nil), route...), tableB)
+					// Routes through the same tables, whichever way their
+					// foreign keys point, have the same relationships
+					key := strings.Join(found, "->")
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+					if !step.up {
+						for i, j := 0, len(found)-1; i < j; i, j = i+1, j-1 {
+							found[i], found[j] = found[j], found[i]
+						}
+					}
+					routes = append(routes, found)
+					continue
+				}
+				if visited[next] || selectedMap[next] {
+					continue
+				}
+				visited[next] = true
+				route = append(route, next)
+				walk(next, step.up)
+				route = route[:len(route)-1]
+				visited[next] = false
+			}
+		}
+	}
+	walk(tableA, false)
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i]) < len(routes[j])
+	})
+	return routes
+}
+
+// isSameRoute reports whether two routes go through the same tables, in either
+// direction
+func isSameRoute(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	forward, backward := true, true
+	for i := range a {
+		forward = forward && a[i] == b[i]
+		backward = backward && a[i] == b[len(b)-1-i]
+	}
+	return forward || backward
+}
+
+// findLCAUsingGonum finds the common descendant of two tables: the table
+// closest to both with foreign key paths to each of them that don't go
+// through other selected tables. Only the tables reached by both searches can
+// be one.
+func findLCAUsingGonum(searchA, searchB *shortestPaths, tableToNode map[string]graph.Node, nodeToTable map[int64]string, selectedMap map[string]bool) (string, graph.Node) {
+	var bestLCA string
+	minDist := -1
+
+	// Since graph is inverted, paths from A to C mean C has FK path to A
+	for id, distToA := range searchA.dist {
+		distToB, ok := searchB.dist[id]
+		if !ok || id == searchA.source.ID() || id == searchB.source.ID() {
+			continue // A or B itself means a direct path, handled by findDirectPath
+		}
+
+		// The closest, then the first by name
+		name := nodeToTable[id]
+		totalDist := distToA + distToB
+		if minDist >= 0 && (totalDist > minDist || (totalDist == minDist && name > bestLCA)) {
+			continue
+		}
+
+		// Check that paths don't go through other selected tables
+		node := tableToNode[name]
+		if !isValidPath(nodesToTables(searchA.pathTo(node), nodeToTable), selectedMap) ||
+			!isValidPath(nodesToTables(searchB.pathTo(node), nodeToTable), selectedMap) {
+			continue
+		}
+		minDist, bestLCA = totalDist, name
+	}
+
+	if bestLCA == "" {
+		return "", nil
+	}
+	return bestLCA, tableToNode[bestLCA]
+}
+
+func calculateLCACardinality(lca, tableA, tableB string, pathCtoA, pathCtoB []string, fkMap map[string][]ForeignKey, columnInfo map[string]ColumnInfo, schema string) []Relationship {
+	choicesCtoA := getForeignKeyChoices(pathCtoA, fkMap)
+	choicesCtoB := getForeignKeyChoices(pathCtoB, fkMap)
+	ambiguous := len(choicesCtoA)*len(choicesCtoB) > 1
+
+	// Build the complete path
+	fullPath := make([]string, 0)
+	// Reverse path from A to C
+	for i := len(pathCtoA) - 1; i >= 0; i-- {
+		fullPath = append(fullPath, pathCtoA[i])
+	}
+	// Add path from C to B (excluding C which is already in the path)
+	if len(pathCtoB) > 1 {
+		fullPath = append(fullPath, pathCtoB[1:]...)
+	}
+
+	// Parse schema from qualified table names
+	schemaA, nameA := parseQualifiedName(tableA)
+	schemaB, nameB := parseQualifiedName(tableB)
+
+	var relationships []Relationship
+	for _, fksCtoA := range choicesCtoA {
+		for _, fksCtoB := range choicesCtoB {
+			// Calculate cardinalities along both paths
+			cardCtoA := calculatePathCardinality(pathCtoA, fksCtoA, columnInfo, schema)
+			cardCtoB := calculatePathCardinality(pathCtoB, fksCtoB, columnInfo, schema)
+
+			if cardCtoA == nil || cardCtoB == nil {
+				continue
+			}
+
+			// Combine cardinalities through the LCA: walking A -> C -> B, the A end
+			// accumulates the A end of the C->A leg and the C end of the C->B leg,
+			// and symmetrically for the B end
+			fromCard := combineCardinality(cardCtoA.ToCardinality, cardCtoB.FromCardinality)
+			toCard := combineCardinality(cardCtoA.FromCardinality, cardCtoB.ToCardinality)
+
+			// Foreign keys in the order they are met along the complete path
+			fks := make([]ForeignKey, 0, len(fksCtoA)+len(fksCtoB))
+			for i := len(fksCtoA) - 1; i >= 0; i-- {
+				fks = append(fks, fksCtoA[i])
+			}
+			fks = append(fks, fksCtoB...)
+
+			relationships = append(relationships, Relationship{
+				From:            Table{Name: nameA, Schema: schemaA},
+				To:              Table{Name: nameB, Schema: schemaB},
+				FromCardinality: fromCard,
+				ToCardinality:   toCard,
+				Path:            fullPath,
+				ForeignKeys:     fks,
+				Label:           getRelationshipLabel(fullPath, fks, ambiguous),
+			})
+		}
+	}
+	return relationships
+}
+
+// getForeignKeyChoices lists every way of following the path with one foreign
+// key per hop, so that tables linked by several constraints give one route each
+func getForeignKeyChoices(pathTables []string, fkMap map[string][]ForeignKey) [][]ForeignKey {
+	choices := [][]ForeignKey{{}}
+	for i := 0; i < len(pathTables)-1; i++ {
+		// The FK may point either way along the path
+		var hopKeys []ForeignKey
+		hopKeys = append(hopKeys, fkMap[pathTables[i]+"->"+pathTables[i+1]]...)
+		hopKeys = append(hopKeys, fkMap[pathTables[i+1]+"->"+pathTables[i]]...)
+
+		var extended [][]ForeignKey
+		for _, choice := range choices {
+			for _, fk := range hopKeys {
+				next := make([]ForeignKey, len(choice), len(choice)+1)
+				copy(next, choice)
+				extended = append(extended, append(next, fk))
+			}
+		}
+		choices = extended
+	}
+	return choices
+}
+
+// getRelationshipLabel names the tables an indirect relationship goes through,
+// or the referencing columns of a direct one. When the same tables are linked
+// by several constraints, indirect labels also list the columns of each hop.
+func getRelationshipLabel(pathTables []string, fks []ForeignKey, ambiguous bool) string {
+	if len(pathTables) <= 2 {
+		if len(fks) == 1 {
+			return strings.Join(fks[0].FromColumns(), ", ")
+		}
+		return ""
+	}
+
+	label := fmt.Sprintf("via %s", strings.Join(pathTables[1:len(pathTables)-1], ", "))
+	if ambiguous {
+		columns := make([]string, len(fks))
+		for i, fk := range fks {
+			columns[i] = strings.Join(fk.FromColumns(), ", ")
+		}
+		label += fmt.Sprintf(" (%s)", strings.Join(columns, "; "))
+	}
+	return label
+}
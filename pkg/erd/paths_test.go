@@ -0,0 +1,245 @@
+package erd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/path"
+)
+
+// floydWarshallCardinalities is how calculateCardinalities found shortest
+// routes before searching from the selected tables: shortest paths between
+// all pairs of tables of the database, thrown away when they go through
+// selected tables. It is kept to compare with. Where shortest paths tie,
+// Floyd-Warshall picked one at random; here the first by the names of its
+// tables is taken, as the breadth-first search does.
+func floydWarshallCardinalities(schemas []string, selectedTables []string, allForeignKeys []ForeignKey, columnInfo map[string]ColumnInfo) []Relationship {
+	schema := schemas[0]
+	g, tableToNode, nodeToTable := buildForeignKeyGraph(allForeignKeys)
+	allPaths, _ := path.FloydWarshall(g)
+
+	fkMap := make(map[string][]ForeignKey)
+	for _, fk := range allForeignKeys {
+		key := getQualifiedName(fk.FromSchema, fk.FromTable) + "->" + getQualifiedName(fk.ToSchema, fk.ToTable)
+		fkMap[key] = append(fkMap[key], fk)
+	}
+	selectedMap := make(map[string]bool)
+	for _, t := range selectedTables {
+		selectedMap[t] = true
+	}
+
+	// between returns the shortest path from one node to another, as tables,
+	// or nil if there is none or it goes through a selected table
+	between := func(from, to graph.Node) []string {
+		if _, _, unique := allPaths.Between(from.ID(), to.ID()); !unique && math.IsInf(allPaths.Weight(from.ID(), to.ID()), 1) {
+			return nil
+		}
+		tables := []string{nodeToTable[from.ID()]}
+		for node := from; node.ID() != to.ID(); {
+			// The first next table by name that is still on a shortest path
+			remaining := allPaths.Weight(node.ID(), to.ID())
+			var next graph.Node
+			for _, neighbour := range graph.NodesOf(g.From(node.ID())) {
+				if allPaths.Weight(neighbour.ID(), to.ID()) == remaining-1 &&
+					(next == nil || nodeToTable[neighbour.ID()] < nodeToTable[next.ID()]) {
+					next = neighbour
+				}
+			}
+			node = next
+			tables = append(tables, nodeToTable[node.ID()])
+		}
+		if !isValidPath(tables, selectedMap) {
+			return nil
+		}
+		return tables
+	}
+	reversed := func(tables []string) []string {
+		reversed := make([]string, len(tables))
+		for i := range tables {
+			reversed[i] = tables[len(tables)-1-i]
+		}
+		return reversed
+	}
+
+	var relationships []Relationship
+	for i, tableA := range selectedTables {
+		for j, tableB := range selectedTables {
+			nodeA, okA := tableToNode[tableA]
+			nodeB, okB := tableToNode[tableB]
+			if i >= j || !okA || !okB {
+				continue
+			}
+
+			var direct []Relationship
+			if route := between(nodeB, nodeA); len(route) > 0 {
+				direct = getRouteRelationships(reversed(route), fkMap, columnInfo, schema)
+			} else if route := between(nodeA, nodeB); len(route) > 0 {
+				direct = getRouteRelationships(reversed(route), fkMap, columnInfo, schema)
+			}
+
+			// Common descendants among all the tables of the database, the
+			// closest, then the first by name
+			var lcaNode graph.Node
+			minDist := -1.0
+			nodes := g.Nodes()
+			for nodes.Next() {
+				node := nodes.Node()
+				if node.ID() == nodeA.ID() || node.ID() == nodeB.ID() {
+					continue
+				}
+				if len(between(nodeA, node)) == 0 || len(between(nodeB, node)) == 0 {
+					continue
+				}
+				dist := allPaths.Weight(nodeA.ID(), node.ID()) + allPaths.Weight(nodeB.ID(), node.ID())
+				if minDist < 0 || dist < minDist || (dist == minDist && nodeToTable[node.ID()] < nodeToTable[lcaNode.ID()]) {
+					minDist, lcaNode = dist, node
+				}
+			}
+			var indirect []Relationship
+			if lcaNode != nil {
+				indirect = calculateLCACardinality(nodeToTable[lcaNode.ID()], tableA, tableB,
+					reversed(between(nodeA, lcaNode)), reversed(between(nodeB, lcaNode)), fkMap, columnInfo, schema)
+			}
+
+			switch {
+			case len(direct) > 0 && (len(indirect) == 0 || len(direct[0].Path) <= len(indirect[0].Path)):
+				relationships = append(relationships, direct...)
+			case len(indirect) > 0:
+				relationships = append(relationships, indirect...)
+			}
+		}
+	}
+	return relationships
+}
+
+func TestCalculateCardinalitiesSelectedTables(t *testing.T) {
+	// c references b, which references a, and c also references a through d
+	// and e: the shortest path from c to a goes through b, which is selected,
+	// so c and a aren't related, the longer path notwithstanding
+	id := []string{"id"}
+	catalog := &Catalog{
+		Tables: []CatalogTable{
+			testTable("a", id, "id"),
+			testTable("b", id, "id", "a_id"),
+			testTable("c", id, "id", "b_id", "d_id"),
+			testTable("d", id, "id", "e_id"),
+			testTable("e", id, "id", "a_id"),
+		},
+		ForeignKeys: []ForeignKey{
+			testForeignKey("b_a_id_fkey", "b", []string{"a_id"}, "a", id),
+			testForeignKey("c_b_id_fkey", "c", []string{"b_id"}, "b", id),
+			testForeignKey("c_d_id_fkey", "c", []string{"d_id"}, "d", id),
+			testForeignKey("d_e_id_fkey", "d", []string{"e_id"}, "e", id),
+			testForeignKey("e_a_id_fkey", "e", []string{"a_id"}, "a", id),
+		},
+	}
+	columnInfo, err := catalog.ColumnInfo(context.Background(), catalog.ForeignKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var routes []string
+	for _, rel := range calculateCardinalities([]string{"public"}, []string{"a", "b", "c"}, catalog.ForeignKeys, columnInfo, 1, DefaultMaxHops) {
+		routes = append(routes, fmt.Sprintf("%v", rel.Path))
+	}
+	if want := []string{"[b a]", "[c b]"}; !reflect.DeepEqual(routes, want) {
+		t.Errorf("routes = %v, want %v", routes, want)
+	}
+
+	// The detour around b is one of the other routes
+	routes = nil
+	for _, rel := range calculateCardinalities([]string{"public"}, []string{"a", "b", "c"}, catalog.ForeignKeys, columnInfo, -1, DefaultMaxHops) {
+		routes = append(routes, fmt.Sprintf("%v", rel.Path))
+	}
+	if want := []string{"[b a]", "[c d e a]", "[c b]"}; !reflect.DeepEqual(routes, want) {
+		t.Errorf("all routes = %v, want %v", routes, want)
+	}
+}
+
+func TestCalculateCardinalitiesFloydWarshall(t *testing.T) {
+	// The searches from the selected tables find what Floyd-Warshall did,
+	// whichever tables are selected
+	catalog := generateCatalog(200)
+	columnInfo, err := catalog.ColumnInfo(context.Background(), catalog.ForeignKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	random := rand.New(rand.NewSource(2))
+	found := 0
+	for i := 0; i < 10; i++ {
+		// Older tables have more tables referencing them
+		var selected []string
+		for _, j := range random.Perm(len(catalog.Tables) / 2)[:2+i] {
+			selected = append(selected, catalog.Tables[j].Name)
+		}
+		want := floydWarshallCardinalities([]string{"public"}, selected, catalog.ForeignKeys, columnInfo)
+		got := calculateCardinalities([]string{"public"}, selected, catalog.ForeignKeys, columnInfo, 1, DefaultMaxHops)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("relationships of %v = %+v, want %+v", selected, got, want)
+		}
+		found += len(got)
+	}
+	if found < 50 {
+		t.Errorf("%d relationships found, too few to compare", found)
+	}
+}
+
+// generateCatalog returns a database of the given number of tables, each
+// referencing up to three of the tables before it, with a junction table
+// between two of them every tenth table
+func generateCatalog(size int) *Catalog {
+	random := rand.New(rand.NewSource(1))
+	id := []string{"id"}
+	catalog := &Catalog{}
+	for i := 0; i < size; i++ {
+		name := fmt.Sprintf("table_%05d", i)
+		table := testTable(name, id, "id")
+		references := 1 + random.Intn(3)
+		junction := i > 0 && i%10 == 0
+		if junction {
+			references = 2
+			table.PrimaryKey = []string{"ref_0_id", "ref_1_id"}
+		}
+		for j := 0; j < references && i > 0; j++ {
+			column := fmt.Sprintf("ref_%d_id", j)
+			table.Columns = append(table.Columns, CatalogColumn{Name: column, DataType: "integer", NotNull: j == 0 || junction})
+			to := fmt.Sprintf("table_%05d", random.Intn(i))
+			catalog.ForeignKeys = append(catalog.ForeignKeys, testForeignKey(name+"_"+column+"_fkey", name, []string{column}, to, id))
+		}
+		catalog.Tables = append(catalog.Tables, table)
+	}
+	return catalog
+}
+
+func BenchmarkCalculateCardinalities(b *testing.B) {
+	for _, size := range []int{500, 1000, 2000, 10000} {
+		catalog := generateCatalog(size)
+		columnInfo, err := catalog.ColumnInfo(context.Background(), catalog.ForeignKeys)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var selected []string
+		for i := 0; i < 10; i++ {
+			selected = append(selected, catalog.Tables[i*size/10].Name)
+		}
+
+		b.Run(fmt.Sprintf("tables=%d/breadth-first", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				calculateCardinalities([]string{"public"}, selected, catalog.ForeignKeys, columnInfo, 1, DefaultMaxHops)
+			}
+		})
+		b.Run(fmt.Sprintf("tables=%d/floyd-warshall", size), func(b *testing.B) {
+			if size > 2000 {
+				b.Skip("Floyd-Warshall takes minutes and gigabytes on this many tables")
+			}
+			for i := 0; i < b.N; i++ {
+				floydWarshallCardinalities([]string{"public"}, selected, catalog.ForeignKeys, columnInfo)
+			}
+		})
+	}
+}
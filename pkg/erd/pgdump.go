@@ -0,0 +1,944 @@
+package erd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Reading the output of pg_dump --schema-only. Only the statements that shape
+// the diagram or the data dictionary are interpreted (CREATE TABLE, ALTER
+// TABLE ... ADD CONSTRAINT, ALTER TABLE ... ALTER COLUMN ... SET NOT NULL or
+// SET DEFAULT, CREATE UNIQUE INDEX and COMMENT ON TABLE or COLUMN);
+// everything else is skipped.
+
+type dumpTokenKind int
+
+const (
+	dumpIdent       dumpTokenKind = iota // Unquoted identifier or keyword, folded to lower case
+	dumpQuotedIdent                      // "Quoted" identifier
+	dumpString                           // String constant, including dollar-quoted ones
+	dumpNumber
+	dumpPunct
+)
+
+type dumpToken struct {
+	kind dumpTokenKind
+	text string // Identifier name, string contents or punctuation
+	raw  string // Text as it appears in the dump
+}
+
+func LoadSchemaFile(path string) (*Catalog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	logger.Printf("Parsing schema file %s...", path)
+	start := time.Now()
+	catalog, err := ParseSchemaDump(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	logger.Printf("Found %d tables and %d foreign keys in schema file (took %v)", len(catalog.Tables), len(catalog.ForeignKeys), time.Since(start))
+	return catalog, nil
+}
+
+func ParseSchemaDump(r io.Reader) (*Catalog, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	statements, err := splitDumpStatements(string(src))
+	if err != nil {
+		return nil, err
+	}
+
+	b := &catalogBuilder{catalog: &Catalog{}, tables: make(map[string]int)}
+	for _, statement := range statements {
+		if err := b.parseStatement(statement); err != nil {
+			return nil, fmt.Errorf("%w in: %s", err, abbreviate(joinDumpTokens(statement), 80))
+		}
+	}
+
+	if err := b.resolveReferences(); err != nil {
+		return nil, err
+	}
+	return b.catalog, nil
+}
+
+// splitDumpStatements tokenizes SQL text into statements separated by
+// semicolons, dropping comments and psql meta-commands such as \connect
+func splitDumpStatements(src string) ([][]dumpToken, error) {
+	var statements [][]dumpToken
+	var current []dumpToken
+	lineStart := true
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		if c == '\n' {
+			lineStart = true
+			i++
+			continue
+		}
+		if c == ' ' || c == '\t' || c == '\r' || c == '\f' {
+			i++
+			continue
+		}
+		atLineStart := lineStart
+		lineStart = false
+
+		start := i
+		switch {
+		case atLineStart && c == '\\':
+			i = skipLine(src, i)
+
+		case strings.HasPrefix(src[i:], "--"):
+			i = skipLine(src, i)
+
+		case strings.HasPrefix(src[i:], "/*"):
+			// Block comments nest in PostgreSQL
+			depth := 0
+			for i < len(src) {
+				if strings.HasPrefix(src[i:], "/*") {
+					depth++
+					i += 2
+				} else if strings.HasPrefix(src[i:], "*/") {
+					depth--
+					i += 2
+					if depth == 0 {
+						break
+					}
+				} else {
+					i++
+				}
+			}
+			if depth > 0 {
+				return nil, fmt.Errorf("unterminated comment at offset %d", start)
+			}
+
+		case c == '\'' || ((c == 'E' || c == 'e') && i+1 < len(src) && src[i+1] == '\''):
+			escapes := c != '\''
+			if escapes {
+				i++
+			}
+			var sb strings.Builder
+			i++
+			closed := false
+			for i < len(src) && !closed {
+				switch {
+				case src[i] == '\'' && i+1 < len(src) && src[i+1] == '\'':
+					sb.WriteByte('\'')
+					i += 2
+				case src[i] == '\'':
+					closed = true
+					i++
+				case escapes && src[i] == '\\' && i+1 < len(src):
+					switch src[i+1] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					default:
+						sb.WriteByte(src[i+1])
+					}
+					i += 2
+				default:
+					sb.WriteByte(src[i])
+					i++
+				}
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated string at offset %d", start)
+			}
+			current = append(current, dumpToken{kind: dumpString, text: sb.String(), raw: src[start:i]})
+
+		case c == '"':
+			var sb strings.Builder
+			i++
+			closed := false
+			for i < len(src) && !closed {
+				switch {
+				case src[i] == '"' && i+1 < len(src) && src[i+1] == '"':
+					sb.WriteByte('"')
+					i += 2
+				case src[i] == '"':
+					closed = true
+					i++
+				default:
+					sb.WriteByte(src[i])
+					i++
+				}
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated quoted identifier at offset %d", start)
+			}
+			current = append(current, dumpToken{kind: dumpQuotedIdent, text: sb.String(), raw: src[start:i]})
+
+		case c == '$' && dollarTag(src[i:]) != "":
+			tag := dollarTag(src[i:])
+			end := strings.Index(src[i+len(tag):], tag)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated dollar-quoted string at offset %d", start)
+			}
+			i += len(tag) + end + len(tag)
+			current = append(current, dumpToken{kind: dumpString, text: src[start+len(tag) : i-len(tag)], raw: src[start:i]})
+
+		case isIdentStart(c):
+			for i < len(src) && isIdentPart(src[i]) {
+				i++
+			}
+			current = append(current, dumpToken{kind: dumpIdent, text: strings.ToLower(src[start:i]), raw: src[start:i]})
+
+		case c >= '0' && c <= '9':
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			current = append(current, dumpToken{kind: dumpNumber, text: src[start:i], raw: src[start:i]})
+
+		case c == ';':
+			i++
+			if len(current) == 0 {
+				continue
+			}
+			statements = append(statements, current)
+			// COPY ... FROM stdin is followed by data lines up to \.
+			if isCopyFromStdin(current) {
+				end := strings.Index(src[i:], "\n\\.")
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated COPY data at offset %d", i)
+				}
+				i = skipLine(src, i+end+1)
+			}
+			current = nil
+
+		case c == ':' && i+1 < len(src) && src[i+1] == ':':
+			i += 2
+			current = append(current, dumpToken{kind: dumpPunct, text: "::", raw: "::"})
+
+		default:
+			i++
+			current = append(current, dumpToken{kind: dumpPunct, text: src[start:i], raw: src[start:i]})
+		}
+	}
+
+	if len(current) > 0 {
+		statements = append(statements, current)
+	}
+	return statements, nil
+}
+
+func skipLine(src string, i int) int {
+	if end := strings.IndexByte(src[i:], '\n'); end >= 0 {
+		return i + end
+	}
+	return len(src)
+}
+
+// dollarTag returns the opening $tag$ of a dollar-quoted string, if s starts with one
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '$' {
+			return s[:i+1]
+		}
+		if !isIdentStart(s[i]) && !(i > 1 && s[i] >= '0' && s[i] <= '9') {
+			return ""
+		}
+	}
+	return ""
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9' || c == '$'
+}
+
+func isCopyFromStdin(statement []dumpToken) bool {
+	if len(statement) == 0 || statement[0].kind != dumpIdent || statement[0].text != "copy" {
+		return false
+	}
+	for _, token := range statement {
+		if token.kind == dumpIdent && token.text == "stdin" {
+			return true
+		}
+	}
+	return false
+}
+
+// joinDumpTokens rebuilds SQL text from tokens, e.g. a column type
+func joinDumpTokens(tokens []dumpToken) string {
+	var sb strings.Builder
+	for i, token := range tokens {
+		if i > 0 {
+			prev := tokens[i-1]
+			glued := prev.kind == dumpPunct && (prev.text == "(" || prev.text == "[" || prev.text == "." || prev.text == "::" || prev.text == ",")
+			if token.kind == dumpPunct {
+				switch token.text {
+				case ")", "]", ",", ".", "::", "[":
+					glued = true
+				case "(":
+					glued = glued || prev.kind == dumpIdent || prev.kind == dumpQuotedIdent
+				}
+			}
+			if !glued {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(token.raw)
+	}
+	return sb.String()
+}
+
+func abbreviate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// dumpParser walks the tokens of one statement
+type dumpParser struct {
+	tokens []dumpToken
+	pos    int
+}
+
+func (p *dumpParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+// next returns the current token and moves past it; callers check done first,
+// or use expect
+func (p *dumpParser) next() dumpToken {
+	token := p.tokens[p.pos]
+	p.pos++
+	return token
+}
+
+// expect is next, failing at the end of the statement with what was expected
+func (p *dumpParser) expect(what string) (dumpToken, error) {
+	if p.done() {
+		return dumpToken{}, fmt.Errorf("expected %s at end of statement", what)
+	}
+	return p.next(), nil
+}
+
+// isKeyword reports whether the upcoming tokens are the given keywords
+func (p *dumpParser) isKeyword(keywords ...string) bool {
+	if p.pos+len(keywords) > len(p.tokens) {
+		return false
+	}
+	for i, keyword := range keywords {
+		token := p.tokens[p.pos+i]
+		if token.kind != dumpIdent || token.text != keyword {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *dumpParser) acceptKeyword(keywords ...string) bool {
+	if !p.isKeyword(keywords...) {
+		return false
+	}
+	p.pos += len(keywords)
+	return true
+}
+
+func (p *dumpParser) isPunct(punct string) bool {
+	return !p.done() && p.tokens[p.pos].kind == dumpPunct && p.tokens[p.pos].text == punct
+}
+
+func (p *dumpParser) parseIdent() (string, error) {
+	token, err := p.expect("identifier")
+	if err != nil {
+		return "", err
+	}
+	if token.kind != dumpIdent && token.kind != dumpQuotedIdent {
+		return "", fmt.Errorf("expected identifier, found %q", token.raw)
+	}
+	return token.text, nil
+}
+
+// parseName reads an optionally schema-qualified name, defaulting to public
+func (p *dumpParser) parseName() (string, string, error) {
+	name, err := p.parseIdent()
+	if err != nil {
+		return "", "", err
+	}
+	if !p.isPunct(".") {
+		return "public", name, nil
+	}
+	p.next()
+	table, err := p.parseIdent()
+	if err != nil {
+		return "", "", err
+	}
+	return name, table, nil
+}
+
+// takeGroup returns the tokens of the parenthesized or bracketed group starting
+// at the current token, including the delimiters
+func (p *dumpParser) takeGroup() []dumpToken {
+	start := p.pos
+	depth := 0
+	for !p.done() {
+		token := p.next()
+		if token.kind == dumpPunct {
+			switch token.text {
+			case "(", "[":
+				depth++
+			case ")", "]":
+				depth--
+			}
+		}
+		if depth == 0 {
+			break
+		}
+	}
+	return p.tokens[start:p.pos]
+}
+
+// parseList reads a parenthesized list, splitting the contents at top-level commas
+func (p *dumpParser) parseList() ([][]dumpToken, error) {
+	if !p.isPunct("(") {
+		return nil, fmt.Errorf("expected (")
+	}
+	group := p.takeGroup()
+	if len(group) < 2 || group[len(group)-1].text != ")" {
+		return nil, fmt.Errorf("unbalanced parentheses")
+	}
+	return splitDumpTokens(group[1:len(group)-1], ","), nil
+}
+
+func (p *dumpParser) parseColumnList() ([]string, error) {
+	elements, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, 0, len(elements))
+	for _, element := range elements {
+		column, err := (&dumpParser{tokens: element}).parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// rest splits the remaining tokens at top-level occurrences of sep
+func (p *dumpParser) rest(sep string) [][]dumpToken {
+	tokens := p.tokens[p.pos:]
+	p.pos = len(p.tokens)
+	return splitDumpTokens(tokens, sep)
+}
+
+func splitDumpTokens(tokens []dumpToken, sep string) [][]dumpToken {
+	var parts [][]dumpToken
+	depth := 0
+	start := 0
+	for i, token := range tokens {
+		if token.kind != dumpPunct {
+			continue
+		}
+		switch token.text {
+		case "(", "[":
+			depth++
+		case ")", "]":
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, tokens[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(tokens) {
+		parts = append(parts, tokens[start:])
+	}
+	return parts
+}
+
+// isColumnConstraintStart reports whether the current token ends a column's
+// type (or DEFAULT expression) and starts one of its constraints
+func (p *dumpParser) isColumnConstraintStart() bool {
+	for _, keyword := range []string{"constraint", "not", "null", "default", "primary", "unique", "references", "check", "collate", "generated"} {
+		if p.isKeyword(keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTableConstraintStart reports whether the current token starts a table
+// constraint rather than a column definition. EXCLUDE isn't reserved, so that
+// pg_dump leaves a column named exclude unquoted: keywords only start a
+// constraint when followed by what the constraint needs.
+func (p *dumpParser) isTableConstraintStart() bool {
+	switch {
+	case p.isKeyword("constraint"), p.isKeyword("primary", "key"), p.isKeyword("foreign", "key"):
+		return true
+	case p.isKeyword("unique"):
+		return p.isFollowedBy("(", "nulls", "using")
+	case p.isKeyword("check"):
+		return p.isFollowedBy("(")
+	case p.isKeyword("exclude"):
+		return p.isFollowedBy("(", "using")
+	}
+	return false
+}
+
+// isFollowedBy reports whether the token after the current one is one of the
+// given keywords or punctuation
+func (p *dumpParser) isFollowedBy(texts ...string) bool {
+	if p.pos+1 >= len(p.tokens) {
+		return false
+	}
+	token := p.tokens[p.pos+1]
+	if token.kind != dumpIdent && token.kind != dumpPunct {
+		return false
+	}
+	for _, text := range texts {
+		if token.text == text {
+			return true
+		}
+	}
+	return false
+}
+
+type catalogBuilder struct {
+	catalog *Catalog
+	tables  map[string]int // schema.table -> index in catalog.Tables
+}
+
+func (b *catalogBuilder) table(schema, name string) *CatalogTable {
+	i, ok := b.tables[schema+"."+name]
+	if !ok {
+		return nil
+	}
+	return &b.catalog.Tables[i]
+}
+
+func (b *catalogBuilder) parseStatement(tokens []dumpToken) error {
+	p := &dumpParser{tokens: tokens}
+	switch {
+	case p.acceptKeyword("create"):
+		p.acceptKeyword("or", "replace")
+		for p.acceptKeyword("global") || p.acceptKeyword("local") || p.acceptKeyword("temporary") || p.acceptKeyword("temp") || p.acceptKeyword("unlogged") {
+		}
+		switch {
+		case p.acceptKeyword("table"):
+			return b.parseCreateTable(p)
+		case p.acceptKeyword("unique", "index"):
+			return b.parseCreateUniqueIndex(p)
+		}
+	case p.acceptKeyword("alter", "table"):
+		return b.parseAlterTable(p)
+	case p.acceptKeyword("comment", "on"):
+		return b.parseComment(p)
+	}
+	return nil
+}
+
+func (b *catalogBuilder) parseCreateTable(p *dumpParser) error {
+	p.acceptKeyword("if", "not", "exists")
+	schema, name, err := p.parseName()
+	if err != nil {
+		return err
+	}
+	if !p.isPunct("(") {
+		return nil // PARTITION OF or OF type: no column list of its own
+	}
+
+	if b.table(schema, name) == nil {
+		b.tables[schema+"."+name] = len(b.catalog.Tables)
+		b.catalog.Tables = append(b.catalog.Tables, CatalogTable{Schema: schema, Name: name})
+	}
+
+	elements, err := p.parseList()
+	if err != nil {
+		return err
+	}
+	for _, element := range elements {
+		e := &dumpParser{tokens: element}
+		switch {
+		case e.done() || e.isKeyword("like"):
+		case e.isTableConstraintStart():
+			err = b.parseTableConstraint(e, schema, name)
+		default:
+			err = b.parseColumnDefinition(e, schema, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *catalogBuilder) parseColumnDefinition(p *dumpParser, schema, table string) error {
+	name, err := p.parseIdent()
+	if err != nil {
+		return err
+	}
+
+	var typeTokens []dumpToken
+	for !p.done() && !p.isColumnConstraintStart() {
+		if p.isPunct("(") || p.isPunct("[") {
+			typeTokens = append(typeTokens, p.takeGroup()...)
+			continue
+		}
+		typeTokens = append(typeTokens, p.next())
+	}
+
+	t := b.table(schema, table)
+	if t == nil {
+		return nil
+	}
+	t.Columns = append(t.Columns, CatalogColumn{Name: name, DataType: joinDumpTokens(typeTokens)})
+
+	for !p.done() {
+		constraintName := ""
+		if p.acceptKeyword("constraint") {
+			if constraintName, err = p.parseIdent(); err != nil {
+				return err
+			}
+		}
+		switch {
+		case p.acceptKeyword("not", "null"):
+			b.setNotNull(schema, table, name)
+		case p.acceptKeyword("primary", "key"):
+			b.addPrimaryKey(schema, table, []string{name})
+		case p.acceptKeyword("unique"):
+			b.addUniqueKey(schema, table, []string{name})
+		case p.acceptKeyword("references"):
+			if constraintName == "" {
+				constraintName = table + "_" + name + "_fkey"
+			}
+			if err := b.parseReferences(p, schema, table, constraintName, []string{name}); err != nil {
+				return err
+			}
+		case p.acceptKeyword("default"):
+			b.setDefault(schema, table, name, p.parseDefault())
+		case p.acceptKeyword("generated"):
+			// GENERATED BY DEFAULT AS IDENTITY isn't a default
+			p.acceptKeyword("by", "default")
+		case p.isPunct("(") || p.isPunct("["):
+			p.takeGroup()
+		default:
+			// NULL, CHECK, COLLATE, ON DELETE, DEFERRABLE...
+			p.next()
+		}
+	}
+	return nil
+}
+
+// parseTableConstraint reads a table constraint, either inside CREATE TABLE
+// or from ALTER TABLE ... ADD. Constraints that don't shape the diagram, such
+// as CHECK or EXCLUDE, are skipped.
+func (b *catalogBuilder) parseTableConstraint(p *dumpParser, schema, table string) error {
+	name := ""
+	if p.acceptKeyword("constraint") {
+		var err error
+		if name, err = p.parseIdent(); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case p.acceptKeyword("primary", "key"):
+		if !p.isPunct("(") {
+			return nil // USING INDEX
+		}
+		columns, err := p.parseColumnList()
+		if err != nil {
+			return err
+		}
+		b.addPrimaryKey(schema, table, columns)
+
+	case p.acceptKeyword("unique"):
+		p.acceptKeyword("nulls", "not", "distinct")
+		p.acceptKeyword("nulls", "distinct")
+		if !p.isPunct("(") {
+			return nil // USING INDEX
+		}
+		columns, err := p.parseColumnList()
+		if err != nil {
+			return err
+		}
+		b.addUniqueKey(schema, table, columns)
+
+	case p.acceptKeyword("foreign", "key"):
+		columns, err := p.parseColumnList()
+		if err != nil {
+			return err
+		}
+		if !p.acceptKeyword("references") {
+			return fmt.Errorf("expected REFERENCES")
+		}
+		if name == "" {
+			name = table + "_" + strings.Join(columns, "_") + "_fkey"
+		}
+		return b.parseReferences(p, schema, table, name, columns)
+	}
+	return nil
+}
+
+func (b *catalogBuilder) parseReferences(p *dumpParser, schema, table, name string, columns []string) error {
+	toSchema, toTable, err := p.parseName()
+	if err != nil {
+		return err
+	}
+
+	// Without a column list the referenced table's primary key is used,
+	// which may not have been seen yet: see resolveReferences
+	var toColumns []string
+	if p.isPunct("(") {
+		if toColumns, err = p.parseColumnList(); err != nil {
+			return err
+		}
+		if len(toColumns) != len(columns) {
+			return fmt.Errorf("foreign key %s has %d referencing and %d referenced columns", name, len(columns), len(toColumns))
+		}
+	}
+
+	fk := ForeignKey{
+		FromSchema:     schema,
+		FromTable:      table,
+		ToSchema:       toSchema,
+		ToTable:        toTable,
+		ConstraintName: name,
+	}
+	for i, column := range columns {
+		pair := ColumnPair{From: column}
+		if toColumns != nil {
+			pair.To = toColumns[i]
+		}
+		fk.Columns = append(fk.Columns, pair)
+	}
+	b.catalog.ForeignKeys = append(b.catalog.ForeignKeys, fk)
+	return nil
+}
+
+// parseDefault reads a DEFAULT expression, up to the next column constraint
+func (p *dumpParser) parseDefault() string {
+	var tokens []dumpToken
+	for !p.done() && (len(tokens) == 0 || !p.isColumnConstraintStart()) {
+		if p.isPunct("(") || p.isPunct("[") {
+			tokens = append(tokens, p.takeGroup()...)
+			continue
+		}
+		tokens = append(tokens, p.next())
+	}
+	return joinDumpTokens(tokens)
+}
+
+func (b *catalogBuilder) parseAlterTable(p *dumpParser) error {
+	p.acceptKeyword("if", "exists")
+	p.acceptKeyword("only")
+	schema, table, err := p.parseName()
+	if err != nil {
+		return err
+	}
+
+	for _, action := range p.rest(",") {
+		a := &dumpParser{tokens: action}
+		switch {
+		case a.acceptKeyword("add"):
+			if a.isTableConstraintStart() {
+				err = b.parseTableConstraint(a, schema, table)
+			} else {
+				a.acceptKeyword("column")
+				a.acceptKeyword("if", "not", "exists")
+				err = b.parseColumnDefinition(a, schema, table)
+			}
+		case a.acceptKeyword("alter"):
+			a.acceptKeyword("column")
+			column, err := a.parseIdent()
+			if err != nil {
+				return err
+			}
+			switch {
+			case a.acceptKeyword("set", "not", "null"):
+				b.setNotNull(schema, table, column)
+			case a.acceptKeyword("set", "default"):
+				b.setDefault(schema, table, column, joinDumpTokens(a.tokens[a.pos:]))
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *catalogBuilder) parseCreateUniqueIndex(p *dumpParser) error {
+	p.acceptKeyword("concurrently")
+	p.acceptKeyword("if", "not", "exists")
+	if !p.isKeyword("on") {
+		if _, err := p.parseIdent(); err != nil {
+			return err
+		}
+	}
+	if !p.acceptKeyword("on") {
+		return fmt.Errorf("expected ON")
+	}
+	p.acceptKeyword("only")
+	schema, table, err := p.parseName()
+	if err != nil {
+		return err
+	}
+	if p.acceptKeyword("using") {
+		if _, err := p.expect("index method"); err != nil {
+			return err
+		}
+	}
+
+	elements, err := p.parseList()
+	if err != nil {
+		return err
+	}
+	columns := make([]string, 0, len(elements))
+	for _, element := range elements {
+		// Expression indexes don't make a set of columns unique
+		if len(element) == 0 || (element[0].kind != dumpIdent && element[0].kind != dumpQuotedIdent) {
+			return nil
+		}
+		if len(element) > 1 && element[1].kind == dumpPunct && element[1].text == "(" {
+			return nil
+		}
+		columns = append(columns, element[0].text)
+	}
+
+	// Partial indexes only make the rows they cover unique
+	for !p.done() {
+		if p.acceptKeyword("where") {
+			return nil
+		}
+		p.next()
+	}
+
+	b.addUniqueKey(schema, table, columns)
+	return nil
+}
+
+// parseComment reads COMMENT ON TABLE or COLUMN; comments on other objects
+// are skipped
+func (b *catalogBuilder) parseComment(p *dumpParser) error {
+	onColumn := p.acceptKeyword("column")
+	if !onColumn && !p.acceptKeyword("table") {
+		return nil
+	}
+
+	// [schema.]table or [schema.]table.column
+	var parts []string
+	for {
+		part, err := p.parseIdent()
+		if err != nil {
+			return err
+		}
+		parts = append(parts, part)
+		if !p.isPunct(".") {
+			break
+		}
+		p.next()
+	}
+	column := ""
+	if onColumn {
+		column, parts = parts[len(parts)-1], parts[:len(parts)-1]
+	}
+	schema, table := "public", ""
+	switch len(parts) {
+	case 1:
+		table = parts[0]
+	case 2:
+		schema, table = parts[0], parts[1]
+	default:
+		return fmt.Errorf("unexpected name in COMMENT ON")
+	}
+
+	if !p.acceptKeyword("is") {
+		return fmt.Errorf("expected IS")
+	}
+	comment := ""
+	if !p.done() && p.tokens[p.pos].kind == dumpString {
+		comment = p.next().text
+	}
+
+	t := b.table(schema, table)
+	if t == nil {
+		return nil
+	}
+	if !onColumn {
+		t.Comment = comment
+		return nil
+	}
+	for i := range t.Columns {
+		if t.Columns[i].Name == column {
+			t.Columns[i].Comment = comment
+		}
+	}
+	return nil
+}
+
+func (b *catalogBuilder) setNotNull(schema, table, column string) {
+	t := b.table(schema, table)
+	if t == nil {
+		return
+	}
+	for i := range t.Columns {
+		if t.Columns[i].Name == column {
+			t.Columns[i].NotNull = true
+		}
+	}
+}
+
+func (b *catalogBuilder) setDefault(schema, table, column, expression string) {
+	t := b.table(schema, table)
+	if t == nil {
+		return
+	}
+	for i := range t.Columns {
+		if t.Columns[i].Name == column {
+			t.Columns[i].Default = expression
+		}
+	}
+}
+
+func (b *catalogBuilder) addPrimaryKey(schema, table string, columns []string) {
+	if t := b.table(schema, table); t != nil {
+		t.PrimaryKey = columns
+	}
+}
+
+func (b *catalogBuilder) addUniqueKey(schema, table string, columns []string) {
+	if t := b.table(schema, table); t != nil {
+		t.UniqueKeys = append(t.UniqueKeys, columns)
+	}
+}
+
+// resolveReferences fills in the referenced columns of foreign keys declared
+// as REFERENCES table without a column list
+func (b *catalogBuilder) resolveReferences() error {
+	for i := range b.catalog.ForeignKeys {
+		fk := &b.catalog.ForeignKeys[i]
+		if len(fk.Columns) == 0 || fk.Columns[0].To != "" {
+			continue
+		}
+		t := b.table(fk.ToSchema, fk.ToTable)
+		if t == nil || len(t.PrimaryKey) != len(fk.Columns) {
+			return fmt.Errorf("foreign key %s does not name the columns it references and %s.%s has no matching primary key", fk.ConstraintName, fk.ToSchema, fk.ToTable)
+		}
+		for j := range fk.Columns {
+			fk.Columns[j].To = t.PrimaryKey[j]
+		}
+	}
+	return nil
+}
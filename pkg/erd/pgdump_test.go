@@ -0,0 +1,213 @@
+package erd
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadTestSchema(t *testing.T, name string) *Catalog {
+	t.Helper()
+	catalog, err := LoadSchemaFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("LoadSchemaFile(%s): %v", name, err)
+	}
+	return catalog
+}
+
+func catalogTableNames(catalog *Catalog) []string {
+	var names []string
+	for _, table := range catalog.Tables {
+		names = append(names, table.Schema+"."+table.Name)
+	}
+	return names
+}
+
+func findCatalogTable(t *testing.T, catalog *Catalog, schema, name string) *CatalogTable {
+	t.Helper()
+	table := catalog.tableIndex()[schema+"."+name]
+	if table == nil {
+		t.Fatalf("table %s.%s not found in %v", schema, name, catalogTableNames(catalog))
+	}
+	return table
+}
+
+func TestParseSchemaDumpQuoting(t *testing.T) {
+	catalog := loadTestSchema(t, "quoting.sql")
+
+	// Nothing in comments, function bodies or COPY data is a table
+	if got, want := catalogTableNames(catalog), []string{"public.notes", "public.after_copy"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("tables = %v, want %v", got, want)
+	}
+
+	notes := findCatalogTable(t, catalog, "public", "notes")
+	want := []CatalogColumn{
+		{Name: "id", DataType: "integer", NotNull: true},
+		{Name: "body", DataType: "text", NotNull: true, Default: `E'line\nbreak; it\'s'`},
+		{Name: "title", DataType: "text", Default: "'it''s; ok'::text"},
+	}
+	if !reflect.DeepEqual(notes.Columns, want) {
+		t.Errorf("columns = %+v, want %+v", notes.Columns, want)
+	}
+}
+
+func TestParseSchemaDumpColumns(t *testing.T) {
+	catalog := loadTestSchema(t, "columns.sql")
+
+	accounts := findCatalogTable(t, catalog, "public", "Accounts")
+	want := []CatalogColumn{
+		{Name: "Id", DataType: "integer", NotNull: true},
+		{Name: "Display Name", DataType: "character varying(100)"},
+		{Name: "exclude", DataType: "boolean", NotNull: true, Default: "false"},
+		{Name: "check", DataType: "integer"},
+		{Name: "serial_no", DataType: "integer"},
+		{Name: "doubled", DataType: "numeric"},
+	}
+	if !reflect.DeepEqual(accounts.Columns, want) {
+		t.Errorf("Accounts columns = %+v, want %+v", accounts.Columns, want)
+	}
+	if want := []string{"Id"}; !reflect.DeepEqual(accounts.PrimaryKey, want) {
+		t.Errorf("Accounts primary key = %v, want %v", accounts.PrimaryKey, want)
+	}
+
+	// EXCLUDE constraints are skipped, but not columns named exclude
+	bookings := findCatalogTable(t, catalog, "public", "bookings")
+	var columns []string
+	for _, column := range bookings.Columns {
+		columns = append(columns, column.Name)
+	}
+	if want := []string{"id", "room", "during", "accountId", "exclude"}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("bookings columns = %v, want %v", columns, want)
+	}
+	if len(bookings.UniqueKeys) != 0 {
+		t.Errorf("bookings unique keys = %v, want none", bookings.UniqueKeys)
+	}
+
+	wantFKs := []ForeignKey{{
+		FromSchema: "public", FromTable: "bookings", ToSchema: "public", ToTable: "Accounts",
+		Columns:        []ColumnPair{{From: "accountId", To: "Id"}},
+		ConstraintName: "Bookings_Account_fkey",
+	}}
+	if !reflect.DeepEqual(catalog.ForeignKeys, wantFKs) {
+		t.Errorf("foreign keys = %+v, want %+v", catalog.ForeignKeys, wantFKs)
+	}
+}
+
+func TestParseSchemaDumpReferences(t *testing.T) {
+	catalog := loadTestSchema(t, "references.sql")
+
+	// Referenced columns default to the primary key, even when declared later
+	want := []ForeignKey{
+		{
+			FromSchema: "public", FromTable: "orders", ToSchema: "public", ToTable: "customers",
+			Columns:        []ColumnPair{{From: "customer_id", To: "id"}},
+			ConstraintName: "orders_customer_id_fkey",
+		},
+		{
+			FromSchema: "public", FromTable: "order_lines", ToSchema: "public", ToTable: "orders",
+			Columns:        []ColumnPair{{From: "tenant_id", To: "tenant_id"}, {From: "order_id", To: "id"}},
+			ConstraintName: "order_lines_order_fkey",
+		},
+		{
+			FromSchema: "public", FromTable: "shipments", ToSchema: "public", ToTable: "orders",
+			Columns:        []ColumnPair{{From: "order_id", To: "id"}, {From: "tenant_id", To: "tenant_id"}},
+			ConstraintName: "shipments_order_fkey",
+		},
+	}
+	if !reflect.DeepEqual(catalog.ForeignKeys, want) {
+		t.Fatalf("foreign keys = %+v, want %+v", catalog.ForeignKeys, want)
+	}
+
+	columnInfo, err := catalog.ColumnInfo(context.Background(), catalog.ForeignKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantInfo := map[string]ColumnInfo{
+		"orders.customer_id":             {IsNullable: true},
+		"order_lines.tenant_id,order_id": {},
+		"shipments.order_id,tenant_id":   {IsNullable: true},
+	}
+	if !reflect.DeepEqual(columnInfo, wantInfo) {
+		t.Errorf("column info = %+v, want %+v", columnInfo, wantInfo)
+	}
+}
+
+func TestParseSchemaDumpUnresolvedReference(t *testing.T) {
+	_, err := ParseSchemaDump(strings.NewReader(`
+CREATE TABLE public.parents (id integer NOT NULL);
+CREATE TABLE public.children (parent_id integer REFERENCES public.parents);
+`))
+	if err == nil || !strings.Contains(err.Error(), "children_parent_id_fkey") {
+		t.Errorf("error = %v, want one naming children_parent_id_fkey", err)
+	}
+}
+
+func TestParseSchemaDumpUniqueIndexes(t *testing.T) {
+	catalog := loadTestSchema(t, "indexes.sql")
+
+	// Expression and partial indexes don't make a set of columns unique
+	users := findCatalogTable(t, catalog, "public", "users")
+	want := [][]string{{"tenant_id", "email"}, {"email"}, {"tenant_id", "login"}, {"id"}}
+	if !reflect.DeepEqual(users.UniqueKeys, want) {
+		t.Errorf("unique keys = %v, want %v", users.UniqueKeys, want)
+	}
+}
+
+func TestParseSchemaDumpComments(t *testing.T) {
+	catalog := loadTestSchema(t, "comments.sql")
+
+	customers := findCatalogTable(t, catalog, "public", "customers")
+	if want := "People who buy things; mostly"; customers.Comment != want {
+		t.Errorf("customers comment = %q, want %q", customers.Comment, want)
+	}
+	if got, want := customers.Columns[0].Comment, ""; got != want {
+		t.Errorf("customers.id comment = %q, want %q", got, want)
+	}
+	if got, want := customers.Columns[1].Comment, "As they'd write it"; got != want {
+		t.Errorf("customers.Full Name comment = %q, want %q", got, want)
+	}
+
+	orders := findCatalogTable(t, catalog, "sales", "orders")
+	if want := "Orders, by 'sales'"; orders.Comment != want {
+		t.Errorf("sales.orders comment = %q, want %q", orders.Comment, want)
+	}
+	if got, want := orders.Columns[0].Comment, "Order number"; got != want {
+		t.Errorf("sales.orders.id comment = %q, want %q", got, want)
+	}
+}
+
+func TestParseSchemaDumpPartitions(t *testing.T) {
+	catalog := loadTestSchema(t, "partitions.sql")
+
+	// Partitions and typed tables have no column list of their own, and are
+	// skipped, but foreign keys are kept whatever the table
+	if got, want := catalogTableNames(catalog), []string{"public.cities", "public.measurements"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tables = %v, want %v", got, want)
+	}
+	var constraints []string
+	for _, fk := range catalog.ForeignKeys {
+		constraints = append(constraints, fk.ConstraintName)
+	}
+	if want := []string{"measurements_city_fkey", "points_x_fkey"}; !reflect.DeepEqual(constraints, want) {
+		t.Errorf("foreign keys = %v, want %v", constraints, want)
+	}
+}
+
+func TestParseSchemaDumpTruncated(t *testing.T) {
+	// Statements cut short fail to parse rather than reading past their end
+	for _, statement := range []string{
+		"CREATE UNIQUE INDEX users_email_key ON public.users USING",
+		"CREATE UNIQUE INDEX users_email_key ON public.users USING btree",
+		"CREATE UNIQUE INDEX users_email_key ON",
+		"CREATE TABLE public.users (id integer REFERENCES",
+		"CREATE TABLE public.users (id integer REFERENCES public.",
+		"ALTER TABLE ONLY public.users ADD CONSTRAINT",
+		"ALTER TABLE ONLY public.users ADD CONSTRAINT users_fkey FOREIGN KEY (id) REFERENCES",
+		"COMMENT ON COLUMN public.users.",
+	} {
+		if _, err := ParseSchemaDump(strings.NewReader(statement + ";")); err == nil {
+			t.Errorf("%s: no error", statement)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package erd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// generatePlantUMLDiagram writes an IE (crow's foot) notation diagram, whose
+// connectors use the same symbols as Mermaid
+func generatePlantUMLDiagram(tables []Table, relationships []Relationship, options RenderOptions) (string, error) {
+	var sb strings.Builder
+
+	sb.WriteString("@startuml\n")
+	// Add comments at the top
+	sb.WriteString("' Generated by https://github.com/Dirac-Software/ersummary\n")
+	sb.WriteString(fmt.Sprintf("' Command: %s\n", options.CommandLine))
+	sb.WriteString("hide circle\n")
+	sb.WriteString("skinparam linetype ortho\n\n")
+
+	ghosts := getGhostTables(tables)
+	for _, table := range tables {
+		if table.Ghost {
+			sb.WriteString(fmt.Sprintf("entity \"%s\" as %s #line.dashed;text:888888 {\n}\n", getQualifiedName(table.Schema, table.Name), getQualifiedTableName(table)))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("entity \"%s\" as %s {\n", getQualifiedName(table.Schema, table.Name), getQualifiedTableName(table)))
+
+		// Primary key columns go above the separator
+		var pkColumns, otherColumns []Column
+		for _, col := range table.Columns {
+			if col.IsPK {
+				pkColumns = append(pkColumns, col)
+			} else {
+				otherColumns = append(otherColumns, col)
+			}
+		}
+		for _, col := range pkColumns {
+			sb.WriteString(fmt.Sprintf("    * %s\n", getPlantUMLColumn(col, options)))
+		}
+		if len(pkColumns) > 0 && len(otherColumns) > 0 {
+			sb.WriteString("    --\n")
+		}
+		for _, col := range otherColumns {
+			sb.WriteString(fmt.Sprintf("    %s\n", getPlantUMLColumn(col, options)))
+		}
+		sb.WriteString("}\n")
+		if table.Comment != "" {
+			sb.WriteString(fmt.Sprintf("note top of %s\n%s\nend note\n", getQualifiedTableName(table), table.Comment))
+		}
+	}
+
+	if len(relationships) > 0 {
+		sb.WriteString("\n")
+	}
+	for _, rel := range relationships {
+		relType := getMermaidRelationType(rel.FromCardinality, rel.ToCardinality)
+		if isGhostRelationship(rel, ghosts) {
+			relType = strings.Replace(relType, "--", "..", 1)
+		}
+		line := fmt.Sprintf("%s %s %s", getQualifiedTableName(rel.From), relType, getQualifiedTableName(rel.To))
+		if rel.Label != "" {
+			line += " : " + rel.Label
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	sb.WriteString("@enduml\n")
+	return sb.String(), nil
+}
+
+func getPlantUMLColumn(col Column, options RenderOptions) string {
+	column := fmt.Sprintf("%s : %s", col.Name, getColumnType(col, options))
+	if col.IsFK {
+		column += " <<FK>>"
+	}
+	if col.IsUnique {
+		column += " <<UK>>"
+	}
+	if col.Comment != "" {
+		column += " // " + strings.Join(strings.Fields(col.Comment), " ")
+	}
+	return column
+}
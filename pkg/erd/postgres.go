@@ -0,0 +1,387 @@
+package erd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Queries of the PostgreSQL data dictionary behind PostgresIntrospector
+
+func getMatchingTables(ctx context.Context, db *sql.DB, schemas []string, tableNames []string, tableRegex string) ([]Table, error) {
+	start := time.Now()
+	tableMap := make(map[string]Table) // Use map to deduplicate
+
+	// Handle exact table names from -tables option, all in one query: an
+	// unqualified name is looked up in each of the schemas, and the schema
+	// and name of each lookup are bound as arrays, as in getTableColumns
+	if len(tableNames) > 0 {
+		var lookupSchemas, lookupNames []string
+		for _, tableName := range tableNames {
+			if strings.Contains(tableName, ".") {
+				parts := strings.SplitN(tableName, ".", 2)
+				lookupSchemas = append(lookupSchemas, parts[0])
+				lookupNames = append(lookupNames, parts[1])
+				continue
+			}
+			for _, schema := range schemas {
+				lookupSchemas = append(lookupSchemas, schema)
+				lookupNames = append(lookupNames, tableName)
+			}
+		}
+
+		query := `
+			SELECT n.nspname, c.relname, COALESCE(obj_description(c.oid, 'pg_class'), '')
+			FROM unnest($1::text[], $2::text[]) AS t(table_schema, table_name)
+			JOIN pg_namespace n ON n.nspname = t.table_schema
+			JOIN pg_class c ON c.relnamespace = n.oid AND c.relname = t.table_name
+			WHERE c.relkind IN ('r', 'p')
+		`
+		if err := queryTables(ctx, db, tableMap, query, pq.Array(lookupSchemas), pq.Array(lookupNames)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Handle regex pattern from -table-regex option
+	if tableRegex != "" {
+		schemaPlaceholders := make([]string, len(schemas))
+		args := make([]interface{}, len(schemas)+1)
+		for i, schema := range schemas {
+			schemaPlaceholders[i] = fmt.Sprintf("$%d", i+1)
+			args[i] = schema
+		}
+		args[len(schemas)] = tableRegex
+
+		query := fmt.Sprintf(`
+			SELECT n.nspname, c.relname, COALESCE(obj_description(c.oid, 'pg_class'), '')
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname IN (%s)
+			AND c.relname ~ $%d
+			AND c.relkind IN ('r', 'p')
+		`, strings.Join(schemaPlaceholders, ", "), len(schemas)+1)
+
+		if err := queryTables(ctx, db, tableMap, query, args...); err != nil {
+			return nil, err
+		}
+	}
+
+	tables := sortedTables(tableMap)
+	logger.Printf("Found %d tables matching criteria (took %v)", len(tables), time.Since(start))
+	return tables, nil
+}
+
+// queryTables adds the tables a query returns, as schema, name and comment
+func queryTables(ctx context.Context, db *sql.DB, tableMap map[string]Table, query string, args ...interface{}) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t Table
+		if err := rows.Scan(&t.Schema, &t.Name, &t.Comment); err != nil {
+			return err
+		}
+		tableMap[t.Schema+"."+t.Name] = t
+	}
+	return rows.Err()
+}
+
+func getAllForeignKeys(ctx context.Context, db *sql.DB) ([]ForeignKey, error) {
+	// One row per column pair, paired up by position in conkey/confkey
+	query := `
+		SELECT
+			con.oid,
+			fn.nspname AS from_schema,
+			fc.relname AS from_table,
+			fa.attname AS from_column,
+			tn.nspname AS to_schema,
+			tc.relname AS to_table,
+			ta.attname AS to_column,
+			con.conname
+		FROM
+			pg_constraint AS con
+			CROSS JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(from_attnum, to_attnum, position)
+			JOIN pg_class AS fc ON fc.oid = con.conrelid
+			JOIN pg_namespace AS fn ON fn.oid = fc.relnamespace
+			JOIN pg_attribute AS fa ON fa.attrelid = con.conrelid AND fa.attnum = k.from_attnum
+			JOIN pg_class AS tc ON tc.oid = con.confrelid
+			JOIN pg_namespace AS tn ON tn.oid = tc.relnamespace
+			JOIN pg_attribute AS ta ON ta.attrelid = con.confrelid AND ta.attnum = k.to_attnum
+		WHERE
+			con.contype = 'f'
+			AND con.conparentid = 0
+		ORDER BY
+			fn.nspname,
+			fc.relname,
+			con.conname,
+			con.oid,
+			k.position
+	`
+
+	logger.Printf("Fetching all foreign keys from database...")
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []ForeignKey
+	lastOID := int64(-1)
+	for rows.Next() {
+		var oid int64
+		var fk ForeignKey
+		var pair ColumnPair
+		err := rows.Scan(&oid, &fk.FromSchema, &fk.FromTable, &pair.From, &fk.ToSchema, &fk.ToTable, &pair.To, &fk.ConstraintName)
+		if err != nil {
+			return nil, err
+		}
+		// Rows of the same constraint are adjacent, in column order
+		if oid != lastOID {
+			foreignKeys = append(foreignKeys, fk)
+			lastOID = oid
+		}
+		last := &foreignKeys[len(foreignKeys)-1]
+		last.Columns = append(last.Columns, pair)
+	}
+
+	logger.Printf("Found %d foreign keys in database (took %v)", len(foreignKeys), time.Since(start))
+	return foreignKeys, rows.Err()
+}
+
+func getColumnInfo(ctx context.Context, db *sql.DB, foreignKeys []ForeignKey) (map[string]ColumnInfo, error) {
+	if len(foreignKeys) == 0 {
+		return make(map[string]ColumnInfo), nil
+	}
+
+	// Schema, table, column and the FK column set it belongs to, one array
+	// each, so that names are bound rather than quoted into the query
+	var schemas, tables, columns, columnSets []string
+	for _, fk := range foreignKeys {
+		key := ColumnInfoKey(fk)
+		for _, column := range fk.FromColumns() {
+			schemas = append(schemas, fk.FromSchema)
+			tables = append(tables, fk.FromTable)
+			columns = append(columns, column)
+			columnSets = append(columnSets, key)
+		}
+	}
+
+	// The FK is nullable if any of its columns is, and unique if some
+	// non-partial unique index (PRIMARY KEY and UNIQUE constraints included)
+	// has only columns of the FK as key columns
+	query := `
+		WITH fk_columns AS (
+			SELECT *
+			FROM unnest($1::text[], $2::text[], $3::text[], $4::text[]) AS t(table_schema, table_name, column_name, column_set)
+		),
+		fk_sets AS (
+			SELECT
+				fk.column_set,
+				c.oid AS table_oid,
+				array_agg(a.attnum) AS attnums,
+				bool_or(NOT a.attnotnull) AS is_nullable
+			FROM fk_columns fk
+			JOIN pg_namespace n ON n.nspname = fk.table_schema
+			JOIN pg_class c ON c.relnamespace = n.oid AND c.relname = fk.table_name
+			JOIN pg_attribute a ON a.attrelid = c.oid AND a.attname = fk.column_name
+			GROUP BY fk.column_set, c.oid
+		)
+		SELECT
+			fk.column_set,
+			fk.is_nullable,
+			EXISTS (
+				SELECT 1
+				FROM pg_index i
+				WHERE i.indrelid = fk.table_oid
+					AND i.indisunique
+					AND i.indpred IS NULL
+					AND (i.indkey::int2[])[0:i.indnkeyatts - 1] <@ fk.attnums
+			) AS has_unique_constraint
+		FROM fk_sets fk
+	`
+
+	logger.Printf("Fetching column info for %d foreign keys...", len(foreignKeys))
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, query, pq.Array(schemas), pq.Array(tables), pq.Array(columns), pq.Array(columnSets))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columnInfo := make(map[string]ColumnInfo)
+
+	for rows.Next() {
+		var columnSet string
+		var isNullable, hasUnique bool
+		if err := rows.Scan(&columnSet, &isNullable, &hasUnique); err != nil {
+			return nil, err
+		}
+		columnInfo[columnSet] = ColumnInfo{
+			IsNullable:          isNullable,
+			HasUniqueConstraint: hasUnique,
+		}
+	}
+
+	logger.Printf("Retrieved column info for %d column sets (took %v)", len(columnInfo), time.Since(start))
+	return columnInfo, rows.Err()
+}
+
+func getTableColumns(ctx context.Context, db *sql.DB, tables []Table, foreignKeys []ForeignKey) ([]Table, error) {
+	if len(tables) == 0 {
+		return []Table{}, nil
+	}
+
+	// Create FK lookup map using qualified names
+	fkLookup := make(map[string]bool)
+	for _, fk := range foreignKeys {
+		qualifiedName := getQualifiedName(fk.FromSchema, fk.FromTable)
+		for _, column := range fk.FromColumns() {
+			fkLookup[qualifiedName+"."+column] = true
+		}
+	}
+
+	// Table comments were fetched with the tables
+	tableComments := make(map[string]string, len(tables))
+	for _, table := range tables {
+		tableComments[table.Schema+"."+table.Name] = table.Comment
+	}
+
+	// The schema and name of each table, as arrays so that the number of
+	// parameters doesn't grow with the number of tables
+	schemas := make([]string, len(tables))
+	names := make([]string, len(tables))
+	for i, table := range tables {
+		schemas[i] = table.Schema
+		names[i] = table.Name
+	}
+
+	query := `
+		SELECT
+			n.nspname,
+			c.relname,
+			a.attname,
+			format_type(a.atttypid, NULL) AS data_type,
+			format_type(a.atttypid, a.atttypmod) AS full_type,
+			EXISTS (
+				SELECT 1
+				FROM pg_index i
+				WHERE i.indrelid = c.oid
+					AND i.indisprimary
+					AND a.attnum = ANY (i.indkey)
+			) AS is_pk,
+			NOT a.attnotnull AS is_nullable,
+			COALESCE(pg_get_expr(d.adbin, d.adrelid), '') AS column_default,
+			COALESCE(col_description(c.oid, a.attnum), '') AS column_comment,
+			-- Unique keys the column belongs to, other than the primary key;
+			-- expression and partial indexes don't make a set of columns unique
+			ARRAY(
+				SELECT ic.relname
+				FROM pg_index i
+				JOIN pg_class ic
+					ON ic.oid = i.indexrelid
+				WHERE i.indrelid = c.oid
+					AND i.indisunique
+					AND NOT i.indisprimary
+					AND i.indexprs IS NULL
+					AND i.indpred IS NULL
+					AND a.attnum = ANY ((i.indkey::int2[])[0:i.indnkeyatts - 1])
+				ORDER BY ic.relname
+			) AS unique_keys
+		FROM
+			unnest($1::text[], $2::text[]) AS t(table_schema, table_name)
+		JOIN pg_namespace n
+			ON n.nspname = t.table_schema
+		JOIN pg_class c
+			ON c.relnamespace = n.oid
+			AND c.relname = t.table_name
+		JOIN pg_attribute a
+			ON a.attrelid = c.oid
+		LEFT JOIN pg_attrdef d
+			ON d.adrelid = a.attrelid
+			AND d.adnum = a.attnum
+		WHERE
+			a.attnum > 0
+			AND NOT a.attisdropped
+		ORDER BY
+			n.nspname,
+			c.relname,
+			a.attnum
+	`
+
+	logger.Printf("Fetching table columns for %d tables", len(tables))
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, query, pq.Array(schemas), pq.Array(names))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tableMap := make(map[string]*Table)
+	uniqueKeys := make(map[string]map[string][]string) // table -> index name -> columns
+
+	for rows.Next() {
+		var schemaName, tableName, columnName, dataType, fullType, columnDefault, columnComment string
+		var isPK, isNullable bool
+		var keyNames []string
+		err := rows.Scan(&schemaName, &tableName, &columnName, &dataType, &fullType, &isPK, &isNullable, &columnDefault, &columnComment, pq.Array(&keyNames))
+		if err != nil {
+			return nil, err
+		}
+
+		tableKey := schemaName + "." + tableName
+		if _, ok := tableMap[tableKey]; !ok {
+			tableMap[tableKey] = &Table{Name: tableName, Schema: schemaName, Comment: tableComments[tableKey], Columns: []Column{}}
+			uniqueKeys[tableKey] = make(map[string][]string)
+		}
+
+		// Use qualified name for FK lookup
+		qualifiedTableName := getQualifiedName(schemaName, tableName)
+		isFK := fkLookup[qualifiedTableName+"."+columnName]
+		tableMap[tableKey].Columns = append(tableMap[tableKey].Columns, Column{
+			Name:       columnName,
+			DataType:   dataType,
+			FullType:   fullType,
+			IsPK:       isPK,
+			IsFK:       isFK,
+			IsNullable: isNullable,
+			Default:    columnDefault,
+			Comment:    columnComment,
+		})
+		for _, keyName := range keyNames {
+			uniqueKeys[tableKey][keyName] = append(uniqueKeys[tableKey][keyName], columnName)
+		}
+	}
+
+	// In the order of the tables given
+	var result []Table
+	for _, t := range tables {
+		tableKey := t.Schema + "." + t.Name
+		table, ok := tableMap[tableKey]
+		if !ok {
+			continue
+		}
+		// Unique keys in index name order, their columns in table order
+		keyNames := make([]string, 0, len(uniqueKeys[tableKey]))
+		for keyName := range uniqueKeys[tableKey] {
+			keyNames = append(keyNames, keyName)
+		}
+		sort.Strings(keyNames)
+		for _, keyName := range keyNames {
+			table.UniqueKeys = append(table.UniqueKeys, uniqueKeys[tableKey][keyName])
+		}
+		markUniqueColumns(table)
+		result = append(result, *table)
+	}
+
+	logger.Printf("Retrieved column details for %d tables (took %v)", len(result), time.Since(start))
+	return result, rows.Err()
+}
@@ -0,0 +1,94 @@
+package erd
+
+import (
+	"sort"
+	"strings"
+)
+
+// RenderOptions are the settings shared by the diagram renderers
+type RenderOptions struct {
+	CommandLine string // Recorded in the output
+	ExactTypes  bool   // Show declared column types rather than simplified ones
+}
+
+// RenderFunc writes tables and their relationships in an output format
+type RenderFunc func(tables []Table, relationships []Relationship, options RenderOptions) (string, error)
+
+// Renderer is an output format
+type Renderer struct {
+	Render RenderFunc
+	// NeedsColumns is set for formats that always show the columns of the
+	// tables, which must then be analyzed with Options.Columns
+	NeedsColumns bool
+}
+
+// renderers maps each format name to its renderer
+var renderers = map[string]Renderer{
+	"mermaid":  {Render: generateMermaidDiagram},
+	"dot":      {Render: generateDotDiagram},
+	"plantuml": {Render: generatePlantUMLDiagram},
+	"dbml":     {Render: generateDBMLDiagram, NeedsColumns: true}, // Refs point at columns
+	"json":     {Render: generateJSONDocument, NeedsColumns: true},
+	"svg":      {Render: generateSVGDiagram},
+	"html":     {Render: generateHTMLReport, NeedsColumns: true}, // The sidebar lists columns
+	"markdown": {Render: generateMarkdownDictionary, NeedsColumns: true},
+}
+
+// RegisterRenderer adds an output format, or replaces an existing one. It is
+// meant to be called from init functions, as renderers aren't locked.
+func RegisterRenderer(format string, renderer Renderer) {
+	renderers[format] = renderer
+}
+
+// LookupRenderer returns the renderer of an output format
+func LookupRenderer(format string) (Renderer, bool) {
+	renderer, ok := renderers[format]
+	return renderer, ok
+}
+
+// Formats returns the names of the output formats, in alphabetical order
+func Formats() []string {
+	formats := make([]string, 0, len(renderers))
+	for format := range renderers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// getGhostTables returns the set of the qualified names of ghost tables
+func getGhostTables(tables []Table) map[string]bool {
+	ghosts := make(map[string]bool)
+	for _, table := range tables {
+		if table.Ghost {
+			ghosts[getQualifiedName(table.Schema, table.Name)] = true
+		}
+	}
+	return ghosts
+}
+
+func isGhostRelationship(rel Relationship, ghosts map[string]bool) bool {
+	return ghosts[getQualifiedName(rel.From.Schema, rel.From.Name)] || ghosts[getQualifiedName(rel.To.Schema, rel.To.Name)]
+}
+
+func getKeyIndicator(col Column) string {
+	var keys []string
+	if col.IsPK {
+		keys = append(keys, "PK")
+	}
+	if col.IsFK {
+		keys = append(keys, "FK")
+	}
+	if col.IsUnique {
+		keys = append(keys, "UK")
+	}
+	return strings.Join(keys, ",")
+}
+
+// getColumnType returns the type to show for a column
+func getColumnType(col Column, options RenderOptions) string {
+	if options.ExactTypes && col.FullType != "" {
+		return col.FullType
+	}
+	return col.DataType
+}
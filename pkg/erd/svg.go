@@ -0,0 +1,338 @@
+package erd
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/graph/simple"
+	"gonum.org/v1/gonum/graph/topo"
+)
+
+// Rendering to SVG without external tools: tables are placed in layers by a
+// longest-path layering of the relationship graph (so that relationships flow
+// left to right), ordered within each layer by the barycenter heuristic, and
+// relationships are drawn as curves with crow's foot ends.
+
+const (
+	svgFontSize   = 12
+	svgCharWidth  = 7.2 // Advance of a monospace character at svgFontSize
+	svgLineHeight = 18
+	svgPadding    = 8
+	svgLayerGap   = 140 // Horizontal space between layers, room for labels
+	svgTableGap   = 40  // Vertical space between tables of a layer
+	svgMargin     = 20
+	svgEdgeGap    = 12 // Spacing of parallel relationships
+)
+
+type svgBox struct {
+	table  Table
+	id     string // Qualified name
+	layer  int
+	x, y   float64
+	width  float64
+	height float64
+}
+
+func generateSVGDiagram(tables []Table, relationships []Relationship, options RenderOptions) (string, error) {
+	boxes, width, height := layoutSVGBoxes(tables, relationships, options)
+	ghosts := getGhostTables(tables)
+	boxByID := make(map[string]*svgBox, len(boxes))
+	for _, box := range boxes {
+		boxByID[box.id] = box
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%.0f\" height=\"%.0f\" viewBox=\"0 0 %.0f %.0f\" font-family=\"monospace\" font-size=\"%d\">\n",
+		width, height, width, height, svgFontSize))
+	// Add comments at the top; "--" is not allowed inside XML comments
+	sb.WriteString("<!-- Generated by https://github.com/Dirac-Software/ersummary -->\n")
+	sb.WriteString(fmt.Sprintf("<!-- Command: %s -->\n", strings.ReplaceAll(options.CommandLine, "--", "- -")))
+	sb.WriteString("<style>\n")
+	sb.WriteString("  .table rect { fill: #ffffff; stroke: #555555; }\n")
+	sb.WriteString("  .table .header { fill: #e0e0e0; }\n")
+	sb.WriteString("  .relationship path, .relationship line { fill: none; stroke: #555555; }\n")
+	sb.WriteString("  .relationship circle { fill: #ffffff; stroke: #555555; }\n")
+	sb.WriteString("  .relationship.indirect > path, .relationship.ghost > path { stroke-dasharray: 6 4; }\n")
+	sb.WriteString("  .table.ghost rect { stroke-dasharray: 4 3; fill: #f8f8f8; }\n")
+	sb.WriteString("  .table.ghost text { fill: #888888; }\n")
+	sb.WriteString("  .relationship text { fill: #333333; font-size: 10px; }\n")
+	sb.WriteString("</style>\n")
+
+	// Relationships first, so that tables are drawn over their ends
+	parallel := make(map[string]int)
+	for _, rel := range relationships {
+		fromID := getQualifiedName(rel.From.Schema, rel.From.Name)
+		toID := getQualifiedName(rel.To.Schema, rel.To.Name)
+		from, to := boxByID[fromID], boxByID[toID]
+		if from == nil || to == nil {
+			continue
+		}
+		pairKey := fromID + "|" + toID
+		if toID < fromID {
+			pairKey = toID + "|" + fromID
+		}
+		offset := float64(parallel[pairKey]) * svgEdgeGap
+		parallel[pairKey]++
+		writeSVGRelationship(&sb, rel, from, to, offset, isGhostRelationship(rel, ghosts))
+	}
+
+	for _, box := range boxes {
+		writeSVGTable(&sb, box, options)
+	}
+
+	sb.WriteString("</svg>\n")
+	return sb.String(), nil
+}
+
+// layoutSVGBoxes sizes and places a box for each table, returning the boxes
+// and the size of the drawing
+func layoutSVGBoxes(tables []Table, relationships []Relationship, options RenderOptions) ([]*svgBox, float64, float64) {
+	boxes := make([]*svgBox, len(tables))
+	idToIndex := make(map[string]int64, len(tables))
+	for i, table := range tables {
+		box := &svgBox{table: table, id: getQualifiedName(table.Schema, table.Name)}
+		// Ghost tables are drawn without their columns
+		if table.Ghost {
+			box.table.Columns = nil
+		}
+		widest := len(box.id)
+		for _, col := range box.table.Columns {
+			if n := len(getSVGColumnText(col, options)); n > widest {
+				widest = n
+			}
+		}
+		box.width = float64(widest)*svgCharWidth + 2*svgPadding
+		box.height = float64(len(box.table.Columns)+1)*svgLineHeight + svgPadding
+		boxes[i] = box
+		idToIndex[box.id] = int64(i)
+	}
+
+	// Graph of the relationships between the tables, without self-loops
+	g := simple.NewDirectedGraph()
+	for i := range boxes {
+		g.AddNode(simple.Node(i))
+	}
+	neighbours := make([][]int, len(boxes))
+	for _, rel := range relationships {
+		from, okFrom := idToIndex[getQualifiedName(rel.From.Schema, rel.From.Name)]
+		to, okTo := idToIndex[getQualifiedName(rel.To.Schema, rel.To.Name)]
+		if !okFrom || !okTo || from == to || g.HasEdgeBetween(from, to) {
+			continue
+		}
+		g.SetEdge(g.NewEdge(simple.Node(from), simple.Node(to)))
+		neighbours[from] = append(neighbours[from], int(to))
+		neighbours[to] = append(neighbours[to], int(from))
+	}
+
+	// Longest-path layering of the condensation: TarjanSCC returns the
+	// strongly connected components in reverse topological order
+	components := topo.TarjanSCC(g)
+	component := make(map[int64]int)
+	for i, nodes := range components {
+		for _, node := range nodes {
+			component[node.ID()] = i
+		}
+	}
+	componentLayer := make([]int, len(components))
+	for i := len(components) - 1; i >= 0; i-- {
+		for _, node := range components[i] {
+			predecessors := g.To(node.ID())
+			for predecessors.Next() {
+				if c := component[predecessors.Node().ID()]; c != i && componentLayer[c]+1 > componentLayer[i] {
+					componentLayer[i] = componentLayer[c] + 1
+				}
+			}
+		}
+	}
+
+	var layers [][]*svgBox
+	for i, box := range boxes {
+		box.layer = componentLayer[component[int64(i)]]
+		for len(layers) <= box.layer {
+			layers = append(layers, nil)
+		}
+		layers[box.layer] = append(layers[box.layer], box)
+	}
+	for _, layer := range layers {
+		sort.Slice(layer, func(i, j int) bool { return layer[i].id < layer[j].id })
+	}
+
+	// Reduce crossings by ordering each layer by the mean position of the
+	// neighbours of its tables, sweeping right then left a few times
+	position := make(map[*svgBox]float64)
+	updatePositions := func(layer []*svgBox) {
+		for i, box := range layer {
+			position[box] = float64(i) / float64(len(layer))
+		}
+	}
+	for _, layer := range layers {
+		updatePositions(layer)
+	}
+	sweep := func(layer []*svgBox) {
+		barycenter := make(map[*svgBox]float64)
+		for _, box := range layer {
+			index := idToIndex[box.id]
+			if len(neighbours[index]) == 0 {
+				barycenter[box] = position[box]
+				continue
+			}
+			sum := 0.0
+			for _, n := range neighbours[index] {
+				sum += position[boxes[n]]
+			}
+			barycenter[box] = sum / float64(len(neighbours[index]))
+		}
+		sort.SliceStable(layer, func(i, j int) bool { return barycenter[layer[i]] < barycenter[layer[j]] })
+		updatePositions(layer)
+	}
+	for iteration := 0; iteration < 4; iteration++ {
+		for l := 1; l < len(layers); l++ {
+			sweep(layers[l])
+		}
+		for l := len(layers) - 2; l >= 0; l-- {
+			sweep(layers[l])
+		}
+	}
+
+	// Place the layers side by side, each centred vertically
+	layerHeights := make([]float64, len(layers))
+	maxHeight := 0.0
+	for l, layer := range layers {
+		for i, box := range layer {
+			if i > 0 {
+				layerHeights[l] += svgTableGap
+			}
+			layerHeights[l] += box.height
+		}
+		maxHeight = math.Max(maxHeight, layerHeights[l])
+	}
+	x := float64(svgMargin)
+	for l, layer := range layers {
+		layerWidth := 0.0
+		y := svgMargin + (maxHeight-layerHeights[l])/2
+		for _, box := range layer {
+			box.x, box.y = x, y
+			y += box.height + svgTableGap
+			layerWidth = math.Max(layerWidth, box.width)
+		}
+		// Centre narrower tables in the layer's column
+		for _, box := range layer {
+			box.x += (layerWidth - box.width) / 2
+		}
+		x += layerWidth + svgLayerGap
+	}
+
+	width := x - svgLayerGap + svgMargin
+	if len(layers) == 0 {
+		width = 2 * svgMargin
+	}
+	// Room on the right for self-references and same-layer relationships
+	width += svgLayerGap / 2
+	return boxes, width, maxHeight + 2*svgMargin
+}
+
+func writeSVGTable(sb *strings.Builder, box *svgBox, options RenderOptions) {
+	class := "table"
+	if box.table.Ghost {
+		class += " ghost"
+	}
+	sb.WriteString(fmt.Sprintf("<g class=\"%s\" data-table=\"%s\">\n", class, html.EscapeString(box.id)))
+	// Comments are shown as tooltips
+	if box.table.Comment != "" {
+		sb.WriteString(fmt.Sprintf("  <title>%s</title>\n", html.EscapeString(box.table.Comment)))
+	}
+	sb.WriteString(fmt.Sprintf("  <rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\"/>\n", box.x, box.y, box.width, box.height))
+	sb.WriteString(fmt.Sprintf("  <rect class=\"header\" x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%d\"/>\n", box.x, box.y, box.width, svgLineHeight))
+	sb.WriteString(fmt.Sprintf("  <text x=\"%.1f\" y=\"%.1f\" font-weight=\"bold\">%s</text>\n",
+		box.x+svgPadding, box.y+svgLineHeight-5, html.EscapeString(box.id)))
+	for i, col := range box.table.Columns {
+		title := ""
+		if col.Comment != "" {
+			title = fmt.Sprintf("<title>%s</title>", html.EscapeString(col.Comment))
+		}
+		sb.WriteString(fmt.Sprintf("  <text x=\"%.1f\" y=\"%.1f\" xml:space=\"preserve\">%s%s</text>\n",
+			box.x+svgPadding, box.y+float64(i+2)*svgLineHeight-5, title, html.EscapeString(getSVGColumnText(col, options))))
+	}
+	sb.WriteString("</g>\n")
+}
+
+func getSVGColumnText(col Column, options RenderOptions) string {
+	text := col.Name + " " + getColumnType(col, options)
+	if key := getKeyIndicator(col); key != "" {
+		text += " " + key
+	}
+	return text
+}
+
+// writeSVGRelationship draws a relationship as a curve leaving and entering
+// the tables horizontally, so that its crow's foot ends are horizontal too
+func writeSVGRelationship(sb *strings.Builder, rel Relationship, from, to *svgBox, offset float64, ghost bool) {
+	x1, y1, side1 := from.x+from.width, from.y+from.height/2+offset, 1.0
+	x2, y2, side2 := to.x, to.y+to.height/2+offset, -1.0
+	switch {
+	case from == to:
+		// Self-reference: a loop on the right of the table
+		y1, y2 = from.y+svgLineHeight/2+offset, from.y+svgLineHeight*1.5+offset
+		x2, side2 = from.x+from.width, 1.0
+	case from.layer == to.layer:
+		// Same layer: both ends on the right
+		x2, side2 = to.x+to.width, 1.0
+	case from.x > to.x:
+		x1, side1 = from.x, -1.0
+		x2, side2 = to.x+to.width, 1.0
+	}
+
+	bend := math.Max(40, math.Abs(x2-x1)/2)
+	if side1 == side2 {
+		bend = math.Max(40, math.Abs(y2-y1)/2)
+	}
+	cx1, cx2 := x1+side1*bend, x2+side2*bend
+
+	class := "relationship"
+	if len(rel.Path) > 2 {
+		class += " indirect"
+	}
+	if ghost {
+		class += " ghost"
+	}
+	sb.WriteString(fmt.Sprintf("<g class=\"%s\" data-from=\"%s\" data-to=\"%s\">\n", class, html.EscapeString(from.id), html.EscapeString(to.id)))
+	if rel.Label != "" {
+		sb.WriteString(fmt.Sprintf("  <title>%s</title>\n", html.EscapeString(rel.Label)))
+	}
+	sb.WriteString(fmt.Sprintf("  <path d=\"M %.1f %.1f C %.1f %.1f, %.1f %.1f, %.1f %.1f\"/>\n", x1, y1, cx1, y1, cx2, y2, x2, y2))
+	writeSVGCrowsFoot(sb, x1, y1, side1, rel.FromCardinality)
+	writeSVGCrowsFoot(sb, x2, y2, side2, rel.ToCardinality)
+	if rel.Label != "" {
+		// Midpoint of the Bezier curve
+		mx := (x1 + 3*cx1 + 3*cx2 + x2) / 8
+		my := (y1+y2)/2 - 4
+		anchor := "middle"
+		if side1 == side2 {
+			// Loops: beside the curve rather than across it
+			mx, my, anchor = mx+4, (y1+y2)/2+4, "start"
+		}
+		sb.WriteString(fmt.Sprintf("  <text x=\"%.1f\" y=\"%.1f\" text-anchor=\"%s\">%s</text>\n", mx, my, anchor, html.EscapeString(rel.Label)))
+	}
+	sb.WriteString("</g>\n")
+}
+
+// writeSVGCrowsFoot draws a cardinality at the point where a relationship
+// meets a table, side being the direction the relationship leaves in: the
+// maximum next to the table, then the minimum
+func writeSVGCrowsFoot(sb *strings.Builder, x, y, side float64, card Cardinality) {
+	if card.Max == "*" {
+		for _, dy := range []float64{-6, 0, 6} {
+			sb.WriteString(fmt.Sprintf("  <line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\"/>\n", x+side*12, y, x, y+dy))
+		}
+	} else {
+		sb.WriteString(fmt.Sprintf("  <line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\"/>\n", x+side*8, y-6, x+side*8, y+6))
+	}
+	if card.Min == "0" {
+		sb.WriteString(fmt.Sprintf("  <circle cx=\"%.1f\" cy=\"%.1f\" r=\"4\"/>\n", x+side*20, y))
+	} else {
+		sb.WriteString(fmt.Sprintf("  <line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\"/>\n", x+side*17, y-6, x+side*17, y+6))
+	}
+}
@@ -0,0 +1,37 @@
+CREATE TABLE public."Accounts" (
+    "Id" integer NOT NULL,
+    "Display Name" character varying(100),
+    exclude boolean DEFAULT false NOT NULL,
+    "check" integer,
+    serial_no integer GENERATED BY DEFAULT AS IDENTITY,
+    doubled numeric GENERATED ALWAYS AS ((("check" * 2))::numeric) STORED
+);
+
+ALTER TABLE public."Accounts" ALTER COLUMN "Id" ADD GENERATED ALWAYS AS IDENTITY (
+    SEQUENCE NAME public."Accounts_Id_seq"
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1
+);
+
+ALTER TABLE ONLY public."Accounts"
+    ADD CONSTRAINT "Accounts_pkey" PRIMARY KEY ("Id");
+
+CREATE TABLE public.bookings (
+    id integer NOT NULL,
+    room integer NOT NULL,
+    during tstzrange NOT NULL,
+    "accountId" integer,
+    EXCLUDE USING gist (room WITH =, during WITH &&)
+);
+
+ALTER TABLE ONLY public.bookings
+    ADD CONSTRAINT bookings_no_overlap EXCLUDE USING gist (room WITH =, during WITH &&);
+
+ALTER TABLE public.bookings
+    ADD exclude text;
+
+ALTER TABLE ONLY public.bookings
+    ADD CONSTRAINT "Bookings_Account_fkey" FOREIGN KEY ("accountId") REFERENCES public."Accounts"("Id");
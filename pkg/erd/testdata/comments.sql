@@ -0,0 +1,26 @@
+CREATE SCHEMA sales;
+
+CREATE TABLE public.customers (
+    id integer NOT NULL,
+    "Full Name" text
+);
+
+CREATE TABLE sales.orders (
+    id integer NOT NULL
+);
+
+COMMENT ON TABLE public.customers IS 'People who buy things; mostly';
+
+COMMENT ON COLUMN public.customers."Full Name" IS E'As they\'d write it';
+
+COMMENT ON TABLE sales.orders IS $$Orders, by 'sales'$$;
+
+COMMENT ON COLUMN sales.orders.id IS 'Order number';
+
+COMMENT ON SCHEMA sales IS 'Not a table';
+
+COMMENT ON FUNCTION public.touch() IS 'Not a table either';
+
+COMMENT ON CONSTRAINT customers_pkey ON public.customers IS 'Not a table either';
+
+COMMENT ON COLUMN public.missing.id IS 'Unknown tables are ignored';
@@ -0,0 +1,24 @@
+CREATE TABLE public.users (
+    id integer NOT NULL,
+    email text NOT NULL,
+    tenant_id integer NOT NULL,
+    login text,
+    deleted_at timestamp with time zone
+);
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_tenant_email_key UNIQUE NULLS NOT DISTINCT (tenant_id, email);
+
+CREATE UNIQUE INDEX users_email_key ON public.users USING btree (email);
+
+CREATE UNIQUE INDEX users_tenant_login_key ON public.users USING btree (tenant_id, login);
+
+CREATE UNIQUE INDEX users_lower_email_key ON public.users USING btree (lower(email));
+
+CREATE UNIQUE INDEX users_login_suffix_key ON public.users USING btree (((login || '_x'::text)));
+
+CREATE UNIQUE INDEX users_live_login_key ON public.users USING btree (login) WHERE (deleted_at IS NULL);
+
+CREATE INDEX users_login_idx ON public.users USING btree (login);
+
+CREATE UNIQUE INDEX "Users_Id_Desc" ON ONLY public.users USING btree (id DESC NULLS LAST);
@@ -0,0 +1,34 @@
+CREATE TYPE public.point_type AS (
+	x integer,
+	y integer
+);
+
+CREATE TABLE public.cities (
+    id integer NOT NULL,
+    name text
+);
+
+CREATE TABLE public.measurements (
+    id integer NOT NULL,
+    city_id integer NOT NULL,
+    logged_on date NOT NULL
+)
+PARTITION BY RANGE (logged_on);
+
+CREATE TABLE public.measurements_2024 PARTITION OF public.measurements
+FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+
+CREATE TABLE public.points OF public.point_type;
+
+CREATE TABLE public.typed_points OF public.point_type (
+    x WITH OPTIONS NOT NULL
+);
+
+ALTER TABLE ONLY public.cities
+    ADD CONSTRAINT cities_pkey PRIMARY KEY (id);
+
+ALTER TABLE public.measurements
+    ADD CONSTRAINT measurements_city_fkey FOREIGN KEY (city_id) REFERENCES public.cities(id);
+
+ALTER TABLE ONLY public.points
+    ADD CONSTRAINT points_x_fkey FOREIGN KEY (x) REFERENCES public.cities(id);
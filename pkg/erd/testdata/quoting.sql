@@ -0,0 +1,46 @@
+--
+-- PostgreSQL database dump
+--
+
+\restrict 4Yt0dBhXzQ
+
+SET statement_timeout = 0;
+SET standard_conforming_strings = on;
+SELECT pg_catalog.set_config('search_path', '', false);
+
+/* A block comment /* nested */ CREATE TABLE public.in_comment (id integer); */
+
+CREATE FUNCTION public.touch() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+  -- CREATE TABLE public.in_function (id integer);
+  NEW.note := 'it''s; fine';
+  RETURN NEW;
+END;
+$$;
+
+CREATE FUNCTION public.tagged(integer) RETURNS text
+    LANGUAGE sql
+    AS $body$ SELECT $$;$$ || $1 || 'CREATE TABLE public.in_tagged (id integer);' $body$;
+
+CREATE TABLE public.notes (
+    id integer NOT NULL,
+    body text DEFAULT E'line\nbreak; it\'s' NOT NULL,
+    title text DEFAULT 'it''s; ok'::text
+);
+
+COPY public.notes (id, body, title) FROM stdin;
+1	CREATE TABLE public.in_copy (id integer);	x
+2	it's; unbalanced	\N
+\.
+
+CREATE TABLE public.after_copy (
+    id integer NOT NULL
+);
+
+--
+-- PostgreSQL database dump complete
+--
+
+\unrestrict 4Yt0dBhXzQ
@@ -0,0 +1,32 @@
+CREATE TABLE public.orders (
+    tenant_id integer NOT NULL,
+    id integer NOT NULL,
+    customer_id integer REFERENCES public.customers,
+    PRIMARY KEY (tenant_id, id)
+);
+
+CREATE TABLE public.order_lines (
+    tenant_id integer NOT NULL,
+    order_id integer NOT NULL,
+    line integer NOT NULL,
+    CONSTRAINT order_lines_order_fkey FOREIGN KEY (tenant_id, order_id) REFERENCES public.orders
+);
+
+CREATE TABLE public.customers (
+    id integer NOT NULL
+);
+
+ALTER TABLE ONLY public.customers
+    ADD CONSTRAINT customers_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY public.order_lines
+    ADD CONSTRAINT order_lines_pkey PRIMARY KEY (tenant_id, order_id, line);
+
+CREATE TABLE public.shipments (
+    tenant_id integer NOT NULL,
+    order_id integer,
+    id integer NOT NULL
+);
+
+ALTER TABLE ONLY public.shipments
+    ADD CONSTRAINT shipments_order_fkey FOREIGN KEY (order_id, tenant_id) REFERENCES public.orders(id, tenant_id) ON DELETE CASCADE;